@@ -0,0 +1,136 @@
+package bootstrap_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+func TestHTTPServer_BindsEagerly(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	_, err = bootstrap.NewHTTPServer(&http.Server{Addr: ln.Addr().String()})
+	if err == nil || !strings.Contains(err.Error(), "cannot listen on "+ln.Addr().String()) {
+		t.Fatalf("expected address conflict error, got %v", err)
+	}
+}
+
+func TestDebugServer(t *testing.T) {
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+
+	debug, err := bootstrap.NewDebugServer("127.0.0.1:0", orc.HealthHandler())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustRegister(t, orc.RegisterV2("debug", debug))
+
+	var ticks atomic.Int32
+	scheduler := bootstrap.NewSchedulerService(&fakeScheduler{}).Add(time.Millisecond, func(ctx context.Context) {
+		ticks.Add(1)
+	})
+	mustRegister(t, orc.RegisterV2("scheduler", scheduler))
+
+	consumed := make(chan struct{})
+	mustRegister(t, orc.RegisterV2("consumer", bootstrap.RunFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(consumed)
+		return ctx.Err()
+	})))
+
+	baseURL := "http://" + debug.Addr().String()
+	serveAndStop(t, orc, func() bool {
+		status, _ := get(baseURL + "/readyz")
+		return status == http.StatusOK && ticks.Load() > 0
+	})
+
+	select {
+	case <-consumed:
+	default:
+		t.Error("expected the consumer context to be cancelled")
+	}
+	if _, err = http.Get(baseURL + "/readyz"); err == nil {
+		t.Error("expected the debug server to be stopped")
+	}
+}
+
+func TestDebugServer_Endpoints(t *testing.T) {
+	debug, err := bootstrap.NewDebugServer("127.0.0.1:0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = debug.Start(context.Background(), func() {})
+	}()
+	defer debug.Stop(context.Background())
+
+	baseURL := "http://" + debug.Addr().String()
+	for path, expected := range map[string]string{
+		"/debug/pprof/":  "goroutine",
+		"/debug/vars":    "memstats",
+		"/debug/pprof/x": "Unknown profile",
+	} {
+		_, body := get(baseURL + path)
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %s to contain %q, got %q", path, expected, body)
+		}
+	}
+}
+
+// fakeScheduler runs tasks the same way as tickrx.Scheduler does
+type fakeScheduler struct {
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func (s *fakeScheduler) Add(interval time.Duration, task func(ctx context.Context)) {
+	s.once.Do(func() { s.stopCh = make(chan struct{}) })
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				task(context.Background())
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (s *fakeScheduler) Stop() {
+	s.once.Do(func() { s.stopCh = make(chan struct{}) })
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// noKeepAliveClient does not reuse connections, so every request reaches the current owner of the listener
+var noKeepAliveClient = &http.Client{
+	Transport: &http.Transport{DisableKeepAlives: true},
+	Timeout:   resultWaitTimeout,
+}
+
+func get(url string) (int, string) {
+	resp, err := noKeepAliveClient.Get(url)
+	if err != nil {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
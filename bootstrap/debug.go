@@ -0,0 +1,33 @@
+package bootstrap
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// NewDebugServer creates HTTPServer exposing pprof profiles under /debug/pprof/, expvar variables under /debug/vars
+// and, if the health handler is given, the /livez, /readyz and /healthz endpoints, e.g. Orchestrator.HealthHandler.
+// The server is meant to listen on a separate port which is not exposed publicly
+func NewDebugServer(addr string, health http.Handler, opts ...httpServerOption) (*HTTPServer, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	if health != nil {
+		mux.Handle("/livez", health)
+		mux.Handle("/readyz", health)
+		mux.Handle("/healthz", health)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return NewHTTPServer(srv, opts...)
+}
@@ -0,0 +1,160 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) index(event string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, e := range r.events {
+		if e == event {
+			return i
+		}
+	}
+	return -1
+}
+
+func recordingService(r *recorder, name string) bootstrap.Service {
+	stopCh := make(chan struct{})
+	return bootstrap.ServiceFunc(func() error {
+		r.add("start " + name)
+		<-stopCh
+		return nil
+	}, func(ctx context.Context) error {
+		r.add("stop " + name)
+		close(stopCh)
+		return nil
+	})
+}
+
+func TestOrchestrator_DependencyOrder(t *testing.T) {
+	r := &recorder{}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+
+	// registered in the reverse order on purpose, dependencies may be declared before registration
+	mustRegister(t, orc.RegisterNamed("http", recordingService(r, "http"), bootstrap.DependsOn("db", "tracer")))
+	mustRegister(t, orc.RegisterNamed("db", recordingService(r, "db"), bootstrap.DependsOn("tracer")))
+	mustRegister(t, orc.RegisterNamed("tracer", recordingService(r, "tracer")))
+
+	serveAndStop(t, orc, func() bool { return r.index("start http") >= 0 })
+
+	// Start of a plain Service blocks, so only the call order is guaranteed, while Stop is synchronous
+	assertBefore(t, r, "stop http", "stop db")
+	assertBefore(t, r, "stop db", "stop tracer")
+}
+
+func TestOrchestrator_RegisterNamedErrors(t *testing.T) {
+	orc := bootstrap.NewOrchestrator()
+	svc := bootstrap.ServiceFunc(func() error { return nil }, func(ctx context.Context) error { return nil })
+
+	mustRegister(t, orc.RegisterNamed("a", svc, bootstrap.DependsOn("b")))
+	mustRegister(t, orc.RegisterNamed("b", svc, bootstrap.DependsOn("c")))
+
+	if err := orc.RegisterNamed("a", svc); !errors.Is(err, bootstrap.ErrDuplicateService) {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+	if err := orc.RegisterNamed("", svc); !errors.Is(err, bootstrap.ErrInvalidServiceName) {
+		t.Errorf("expected invalid name error, got %v", err)
+	}
+
+	err := orc.RegisterNamed("c", svc, bootstrap.DependsOn("a"))
+	if !errors.Is(err, bootstrap.ErrDependencyCycle) {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+	if expected := "service dependency cycle detected: c -> a -> b -> c"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	if err = orc.Serve(); !errors.Is(err, bootstrap.ErrUnknownDependency) {
+		t.Errorf("expected unknown dependency error, got %v", err)
+	}
+}
+
+func TestOrchestrator_ServiceStopTimeout(t *testing.T) {
+	deadlineCh := make(chan time.Duration, 1)
+	stopCh := make(chan struct{})
+	var started atomic.Bool
+	svc := bootstrap.ServiceFunc(func() error {
+		started.Store(true)
+		<-stopCh
+		return nil
+	}, func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		deadlineCh <- time.Until(deadline)
+		close(stopCh)
+		return nil
+	})
+
+	orc := bootstrap.NewOrchestrator(
+		bootstrap.WithLogger(bootstrap.NewNoopLogger()),
+		bootstrap.WithShutdownTimeout(time.Hour),
+	)
+	mustRegister(t, orc.RegisterNamed("svc", svc, bootstrap.WithServiceStopTimeout(time.Minute)))
+
+	serveAndStop(t, orc, started.Load)
+
+	if timeout := <-deadlineCh; timeout > time.Minute || timeout < 59*time.Second {
+		t.Errorf("expected service timeout to be used, got %s", timeout)
+	}
+}
+
+func mustRegister(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected registration error: %v", err)
+	}
+}
+
+func assertBefore(t *testing.T, r *recorder, first, second string) {
+	t.Helper()
+	i, j := r.index(first), r.index(second)
+	if i < 0 || j < 0 || i > j {
+		t.Errorf("expected %q to happen before %q, got %v", first, second, r.events)
+	}
+}
+
+// serveAndStop runs Serve, waits for the condition and stops the orchestrator
+func serveAndStop(t *testing.T, orc *bootstrap.Orchestrator, started func() bool) {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- orc.Serve()
+	}()
+
+	deadline := time.Now().Add(resultWaitTimeout)
+	for !started() {
+		if time.Now().After(deadline) {
+			t.Fatal("services were not started within timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	orc.Stop()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error is raised from serve function: %v", err)
+		}
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("failed to shutdown orchestrator within timeout")
+	}
+}
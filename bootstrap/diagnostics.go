@@ -0,0 +1,43 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime/pprof"
+	"strings"
+)
+
+// serviceLabel is the profiler label set for goroutines of the service, goroutines started by the service inherit it
+const serviceLabel = "service"
+
+// withServiceLabel runs f with the service profiler label
+func withServiceLabel(ctx context.Context, name string, f func(ctx context.Context)) {
+	pprof.Do(ctx, pprof.Labels(serviceLabel, name), f)
+}
+
+// goroutineDump returns stacks of goroutines labeled with the service name.
+// Goroutines with identical stacks are grouped as the goroutine profile does
+func goroutineDump(name string) string {
+	buf := &bytes.Buffer{}
+	if err := pprof.Lookup("goroutine").WriteTo(buf, 1); err != nil {
+		return ""
+	}
+
+	key, _ := json.Marshal(serviceLabel)
+	value, _ := json.Marshal(name)
+	label := string(key) + ":" + string(value)
+
+	var result strings.Builder
+	// the first record is the profile header, records are separated by empty lines
+	for _, record := range strings.Split(buf.String(), "\n\n") {
+		for _, line := range strings.Split(record, "\n") {
+			if strings.HasPrefix(line, "# labels: ") && strings.Contains(line, label) {
+				result.WriteString(record)
+				result.WriteString("\n\n")
+				break
+			}
+		}
+	}
+	return result.String()
+}
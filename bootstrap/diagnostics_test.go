@@ -0,0 +1,149 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+type argsLogger struct {
+	mu   sync.Mutex
+	logs map[string][]any
+}
+
+func (l *argsLogger) Info(msg string, args ...any) {
+	l.add(msg, args)
+}
+
+func (l *argsLogger) Error(msg string, args ...any) {
+	l.add(msg, args)
+}
+
+func (l *argsLogger) add(msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.logs == nil {
+		l.logs = make(map[string][]any)
+	}
+	l.logs[msg] = args
+}
+
+// arg returns the value of the argument of the last message logged
+func (l *argsLogger) arg(msg, key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	args, ok := l.logs[msg]
+	for i := 0; ok && i+1 < len(args); i += 2 {
+		if args[i] == key {
+			return fmt.Sprint(args[i+1]), true
+		}
+	}
+	return "", false
+}
+
+// stuckService ignores the stop context, so its Stop hangs until released
+func stuckService(release <-chan struct{}, stopping chan<- struct{}) *serviceV2 {
+	return &serviceV2{
+		start: func(ctx context.Context, ready func()) error {
+			ready()
+			<-ctx.Done()
+			return nil
+		},
+		stop: func(ctx context.Context) error {
+			go waitForRelease(release)
+			close(stopping)
+			waitForRelease(release)
+			return nil
+		},
+	}
+}
+
+func waitForRelease(release <-chan struct{}) {
+	<-release
+}
+
+func TestOrchestrator_ShutdownTimeoutDiagnostics(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	logger := &argsLogger{}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(logger))
+	mustRegister(t, orc.RegisterV2("stuck", stuckService(release, make(chan struct{})), bootstrap.WithServiceStopTimeout(100*time.Millisecond)))
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(&recorder{}, "http")))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- orc.Run(context.Background())
+	}()
+	waitFor(t, func() bool { return orc.Readiness(context.Background()).OK() })
+	orc.Stop()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("failed to shutdown orchestrator within timeout")
+	}
+	if !errors.Is(err, bootstrap.ErrShutdownTimeout) {
+		t.Fatalf("expected shutdown timeout error, got %v", err)
+	}
+	if expected := "service stuck: service has not stopped within shutdown timeout: 100ms"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+
+	dump, ok := logger.arg("service has exceeded shutdown timeout", "goroutines")
+	if !ok || strings.Count(dump, "bootstrap_test.waitForRelease") != 2 {
+		t.Errorf("expected goroutines of the service to be dumped, got:\n%s", dump)
+	}
+	if strings.Contains(dump, "untilCancelled") {
+		t.Errorf("goroutines of other services must not be dumped, got:\n%s", dump)
+	}
+}
+
+func TestOrchestrator_ForcedExit(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	exitCh := make(chan int, 1)
+	logger := &argsLogger{}
+	orc := bootstrap.NewOrchestrator(
+		bootstrap.WithLogger(logger),
+		bootstrap.WithStopSignals(syscall.SIGUSR1),
+		bootstrap.WithForceExitCode(3),
+		bootstrap.WithExitFunc(func(code int) {
+			exitCh <- code
+		}),
+	)
+	stopping := make(chan struct{})
+	mustRegister(t, orc.RegisterV2("stuck", stuckService(release, stopping)))
+
+	go func() {
+		_ = orc.Run(context.Background())
+	}()
+	waitFor(t, func() bool { return orc.Readiness(context.Background()).OK() })
+	orc.Stop()
+	<-stopping
+
+	if err := sendSignal(syscall.SIGUSR1); err != nil {
+		t.Fatalf("failed to send signal to current process: %v", err)
+	}
+
+	select {
+	case code := <-exitCh:
+		if code != 3 {
+			t.Errorf("expected exit code 3, got %d", code)
+		}
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("expected forced exit")
+	}
+	if dump, _ := logger.arg("service is still stopping", "goroutines"); !strings.Contains(dump, "waitForRelease") {
+		t.Errorf("expected goroutines of the stuck service to be dumped, got:\n%s", dump)
+	}
+}
@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"time"
+)
+
+// EventType is the type of the lifecycle event
+type EventType string
+
+const (
+	// EventStarting is emitted right before Start of the service is called, including restarts
+	EventStarting EventType = "starting"
+	// EventStarted is emitted once the service is ready
+	EventStarted EventType = "started"
+	// EventStopping is emitted right before Stop of the service is called
+	EventStopping EventType = "stopping"
+	// EventStopped is emitted once the service is stopped, Err is set if the shutdown has failed
+	EventStopped EventType = "stopped"
+	// EventFailed is emitted when Start of the service returns an error before shutdown
+	EventFailed EventType = "failed"
+)
+
+// Event describes the change of the service lifecycle
+type Event struct {
+	Type    EventType
+	Service string
+	Time    time.Time
+	// Duration is the time passed since the corresponding starting or stopping event
+	// for started and stopped events, zero otherwise
+	Duration time.Duration
+	// Err is the cause of failed and stopped events
+	Err error
+}
+
+// EventHandler handles lifecycle events. Handlers are called synchronously from goroutines serving services,
+// so they must be fast and safe for concurrent use
+type EventHandler func(e Event)
+
+// Subscribe registers the handler of lifecycle events
+func (o *Orchestrator) Subscribe(h EventHandler) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.handlers = append(o.handlers, h)
+}
+
+func (o *Orchestrator) emit(t EventType, service string, since time.Time, err error) {
+	o.mu.Lock()
+	handlers := o.handlers
+	o.mu.Unlock()
+	if len(handlers) == 0 {
+		return
+	}
+
+	e := Event{Type: t, Service: service, Time: time.Now(), Err: err}
+	if !since.IsZero() {
+		e.Duration = e.Time.Sub(since)
+	}
+	for _, h := range handlers {
+		h(e)
+	}
+}
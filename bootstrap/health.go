@@ -0,0 +1,196 @@
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+var (
+	errNotStarted   = errors.New("not started")
+	errShuttingDown = errors.New("shutting down")
+)
+
+// HealthChecker is implemented by services which are able to report their health.
+// The check is a part of the liveness report, so it must fail only if the service is not able to recover by itself
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheckFunc is an adapter to allow the use of ordinary functions as HealthChecker
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheck calls f(ctx)
+func (f HealthCheckFunc) HealthCheck(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ReadyNotifier is implemented by services which need time to become ready after Start is called.
+// The returned channel must be closed once the service is ready, dependent services are started after that.
+// Services which do not implement ReadyNotifier are ready as soon as they are started
+type ReadyNotifier interface {
+	Ready() <-chan struct{}
+}
+
+// CheckResult is the result of a single check
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthReport is the aggregated result of checks
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// OK reports whether all checks have passed
+func (r HealthReport) OK() bool {
+	return r.Status == StatusOK
+}
+
+func (r *HealthReport) add(name string, err error) {
+	if err != nil {
+		r.Status = StatusFail
+		r.set(name, CheckResult{Status: StatusFail, Error: err.Error()})
+		return
+	}
+	r.set(name, CheckResult{Status: StatusOK})
+}
+
+func (r *HealthReport) set(name string, result CheckResult) {
+	if r.Checks == nil {
+		r.Checks = make(map[string]CheckResult)
+	}
+	r.Checks[name] = result
+}
+
+// RegisterHealthCheck registers the check which is not bound to any service, e.g. a connection pool check.
+// The check is a part of the liveness report
+func (o *Orchestrator) RegisterHealthCheck(name string, check HealthChecker) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.checks = append(o.checks, namedCheck{name: name, check: check})
+}
+
+// Liveness runs checks of running services implementing HealthChecker and checks registered with
+// RegisterHealthCheck. Services which are not started yet are not checked
+func (o *Orchestrator) Liveness(ctx context.Context) HealthReport {
+	o.mu.Lock()
+	checks := make([]namedCheck, len(o.checks), len(o.checks)+len(o.current))
+	copy(checks, o.checks)
+	for _, l := range o.current {
+		if hc, ok := l.svc.(HealthChecker); ok && l.isReady() {
+			checks = append(checks, namedCheck{name: l.name, check: hc})
+		}
+	}
+	o.mu.Unlock()
+
+	return o.runChecks(ctx, checks)
+}
+
+// Readiness reports whether every service is ready. The report fails as soon as shutdown begins
+func (o *Orchestrator) Readiness(context.Context) HealthReport {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	report := HealthReport{Status: StatusOK}
+	if o.current == nil {
+		report.Status = StatusFail
+	}
+	for _, e := range o.entries {
+		var err error
+		switch l := o.lifecycle(e.name); {
+		case o.shuttingDown:
+			err = errShuttingDown
+		case l != nil && l.failure != nil:
+			// the failure of a non-critical service does not affect the aggregated status
+			report.set(e.name, CheckResult{Status: StatusFail, Error: l.failure.Error()})
+			continue
+		case l == nil || !l.isReady():
+			err = errNotStarted
+		}
+		report.add(e.name, err)
+	}
+	return report
+}
+
+// Health combines Liveness and Readiness reports
+func (o *Orchestrator) Health(ctx context.Context) HealthReport {
+	report := o.Readiness(ctx)
+	liveness := o.Liveness(ctx)
+	for name, result := range liveness.Checks {
+		// liveness failures take precedence over readiness ones, the reason is more specific
+		if prev, ok := report.Checks[name]; ok && result.Status == StatusOK {
+			result = prev
+		}
+		report.set(name, result)
+	}
+	if !liveness.OK() {
+		report.Status = StatusFail
+	}
+	return report
+}
+
+// HealthHandler returns the handler serving /livez, /readyz and /healthz endpoints.
+// The report is encoded as JSON, the status code is 503 if any check fails
+func (o *Orchestrator) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/livez", healthHandler(o.Liveness))
+	mux.Handle("/readyz", healthHandler(o.Readiness))
+	mux.Handle("/healthz", healthHandler(o.Health))
+	return mux
+}
+
+func healthHandler(report func(ctx context.Context) HealthReport) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result := report(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if result.OK() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+type namedCheck struct {
+	name  string
+	check HealthChecker
+}
+
+// runChecks runs checks concurrently, each check is limited by the health check timeout
+func (o *Orchestrator) runChecks(ctx context.Context, checks []namedCheck) HealthReport {
+	if o.healthCheckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.healthCheckTimeout)
+		defer cancel()
+	}
+
+	errs := make([]error, len(checks))
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = checks[i].check.HealthCheck(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: StatusOK}
+	for i, c := range checks {
+		report.add(c.name, errs[i])
+	}
+	return report
+}
@@ -0,0 +1,135 @@
+package bootstrap_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+type healthService struct {
+	readyCh   chan struct{}
+	stopCh    chan struct{}
+	stoppedCh chan struct{}
+	healthErr error
+}
+
+func newHealthService() *healthService {
+	return &healthService{
+		readyCh:   make(chan struct{}),
+		stopCh:    make(chan struct{}),
+		stoppedCh: make(chan struct{}),
+	}
+}
+
+func (s *healthService) Start() error {
+	<-s.stopCh
+	return nil
+}
+
+func (s *healthService) Stop(context.Context) error {
+	close(s.stopCh)
+	close(s.stoppedCh)
+	return nil
+}
+
+func (s *healthService) Ready() <-chan struct{} {
+	return s.readyCh
+}
+
+func (s *healthService) HealthCheck(context.Context) error {
+	return s.healthErr
+}
+
+func TestOrchestrator_HealthHandler(t *testing.T) {
+	svc := newHealthService()
+	svc.healthErr = errors.New("queue is stuck")
+
+	orc := bootstrap.NewOrchestrator(
+		bootstrap.WithLogger(bootstrap.NewNoopLogger()),
+		bootstrap.WithDrainDelay(300*time.Millisecond),
+	)
+	mustRegister(t, orc.RegisterNamed("worker", svc))
+	orc.RegisterHealthCheck("db", bootstrap.HealthCheckFunc(func(ctx context.Context) error {
+		return nil
+	}))
+	handler := orc.HealthHandler()
+
+	if status, _ := healthRequest(handler, "/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness to fail before Serve, got %d", status)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- orc.Serve()
+	}()
+
+	waitFor(t, func() bool {
+		_, report := healthRequest(handler, "/readyz")
+		return report.Checks["worker"].Error == "not started"
+	})
+	close(svc.readyCh)
+	waitFor(t, func() bool {
+		status, _ := healthRequest(handler, "/readyz")
+		return status == http.StatusOK
+	})
+
+	status, report := healthRequest(handler, "/livez")
+	if status != http.StatusServiceUnavailable || report.Checks["worker"].Error != "queue is stuck" {
+		t.Errorf("unexpected liveness report %d %+v", status, report)
+	}
+	if report.Checks["db"].Status != bootstrap.StatusOK {
+		t.Errorf("expected db check to pass, got %+v", report.Checks["db"])
+	}
+
+	status, report = healthRequest(handler, "/healthz")
+	if status != http.StatusServiceUnavailable || report.Checks["worker"].Status != bootstrap.StatusFail {
+		t.Errorf("unexpected health report %d %+v", status, report)
+	}
+
+	orc.Stop()
+
+	// readiness is failed first, while the service is still running during the drain delay
+	waitFor(t, func() bool {
+		status, _ := healthRequest(handler, "/readyz")
+		return status == http.StatusServiceUnavailable
+	})
+	select {
+	case <-svc.stoppedCh:
+		t.Fatal("service must not be stopped before the drain delay passes")
+	default:
+	}
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error is raised from serve function: %v", err)
+		}
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("failed to shutdown orchestrator within timeout")
+	}
+}
+
+func healthRequest(h http.Handler, path string) (int, bootstrap.HealthReport) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report bootstrap.HealthReport
+	_ = json.NewDecoder(rec.Body).Decode(&report)
+	return rec.Code, report
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(resultWaitTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met within timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
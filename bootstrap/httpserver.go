@@ -0,0 +1,96 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+type httpServerOption func(s *HTTPServer)
+
+// WithTLSFiles enables TLS using the given certificate and key files.
+// TLS is enabled as well if http.Server.TLSConfig contains certificates
+func WithTLSFiles(certFile, keyFile string) httpServerOption {
+	return func(s *HTTPServer) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithListener makes the server use the given listener instead of binding http.Server.Addr
+func WithListener(ln net.Listener) httpServerOption {
+	return func(s *HTTPServer) {
+		s.listener = ln
+	}
+}
+
+// HTTPServer is ServiceV2 serving HTTP or HTTPS requests
+type HTTPServer struct {
+	server   *http.Server
+	listener net.Listener
+	certFile string
+	keyFile  string
+}
+
+// NewHTTPServer binds the listener eagerly, so the address conflict fails immediately rather than
+// after the orchestrator has started other services. The service is ready as soon as it is started.
+// The listener is created by Listen, so the inherited one is used if present
+func NewHTTPServer(srv *http.Server, opts ...httpServerOption) (*HTTPServer, error) {
+	s := &HTTPServer{server: srv}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.listener != nil {
+		return s, nil
+	}
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+		if s.isTLS() {
+			addr = ":https"
+		}
+	}
+	ln, err := Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %w", addr, err)
+	}
+	s.listener = ln
+	return s, nil
+}
+
+// Addr returns the address the server is listening on, it is useful when the port is chosen by the system
+func (s *HTTPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Start serves requests until the server is stopped
+func (s *HTTPServer) Start(_ context.Context, ready func()) error {
+	ready()
+
+	var err error
+	if s.isTLS() {
+		err = s.server.ServeTLS(s.listener, s.certFile, s.keyFile)
+	} else {
+		err = s.server.Serve(s.listener)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Stop gracefully shuts down the server
+func (s *HTTPServer) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+func (s *HTTPServer) isTLS() bool {
+	if s.certFile != "" {
+		return true
+	}
+	c := s.server.TLSConfig
+	return c != nil && (len(c.Certificates) > 0 || c.GetCertificate != nil)
+}
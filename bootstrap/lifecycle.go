@@ -0,0 +1,311 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// session is the state of a single Run call shared by the services
+type session struct {
+	// ctx is cancelled when services must be stopped
+	ctx    context.Context
+	wg     sync.WaitGroup
+	errCh  chan error
+	exitCh chan string
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// addErr collects errors which are returned by Run along with the cause of shutdown
+func (s *session) addErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+// lifecycle tracks the state of the service during a single Run call
+type lifecycle struct {
+	*entry
+	deps       []*lifecycle
+	dependents []*lifecycle
+	started    chan struct{}
+	stopped    chan struct{}
+	// startedOnce closes started, which is not reopened when the service is restarted
+	startedOnce sync.Once
+	running     bool
+	stopping    atomic.Bool
+	// failure is the error of the failed non-critical service, guarded by Orchestrator.mu
+	failure error
+}
+
+// isReady reports whether the service is started and ready
+func (l *lifecycle) isReady() bool {
+	return isClosed(l.started)
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func (o *Orchestrator) setLifecycles(ls []*lifecycle) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.current = ls
+	o.shuttingDown = false
+}
+
+// lifecycle returns the lifecycle of the service being served, must be called with mu held
+func (o *Orchestrator) lifecycle(name string) *lifecycle {
+	for _, l := range o.current {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+func (o *Orchestrator) lifecycles() []*lifecycle {
+	byName := make(map[string]*lifecycle, len(o.entries))
+	result := make([]*lifecycle, 0, len(o.entries))
+	for _, e := range o.entries {
+		l := &lifecycle{entry: e, started: make(chan struct{}), stopped: make(chan struct{})}
+		byName[e.name] = l
+		result = append(result, l)
+	}
+	for _, l := range result {
+		for _, dep := range l.entry.deps {
+			d := byName[dep]
+			l.deps = append(l.deps, d)
+			d.dependents = append(d.dependents, l)
+		}
+	}
+	return result
+}
+
+// readyFunc returns the ready callback of the start attempt, EventStarted is emitted once per attempt
+func (o *Orchestrator) readyFunc(l *lifecycle, startedAt time.Time) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.startedOnce.Do(func() { close(l.started) })
+			o.emit(EventStarted, l.name, startedAt, nil)
+		})
+	}
+}
+
+// serveLifecycle starts the service once its dependencies are started and stops it once its dependents are stopped
+func (o *Orchestrator) serveLifecycle(s *session, l *lifecycle) {
+	defer s.wg.Done()
+	defer close(l.stopped)
+
+	// the service context is cancelled right before Stop is called, so the dependents are stopped first
+	runCtx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
+	exited := make(chan struct{})
+
+	// the context is checked explicitly since select does not prefer any of the ready channels
+	if waitAll(s.ctx, l.deps, func(d *lifecycle) <-chan struct{} { return d.started }) && s.ctx.Err() == nil {
+		l.running = true
+		go withServiceLabel(runCtx, l.name, func(ctx context.Context) {
+			o.run(s, ctx, l, exited)
+		})
+	}
+
+	<-s.ctx.Done()
+
+	// dependents are stopped first
+	waitAll(context.Background(), l.dependents, func(d *lifecycle) <-chan struct{} { return d.stopped })
+
+	if !l.running {
+		return
+	}
+
+	timeout := o.stopTimeout(l)
+	stopCtx, stopCancel := o.shutdownContext(timeout)
+	defer stopCancel()
+
+	stoppingAt := time.Now()
+	l.stopping.Store(true)
+	o.emit(EventStopping, l.name, time.Time{}, nil)
+
+	runCancel()
+	err := o.stop(stopCtx, l, exited)
+	if err != nil && stopCtx.Err() == context.DeadlineExceeded {
+		// Stop might ignore the context, so it is abandoned, since it is not possible to interrupt it
+		o.logger.Error(
+			"service has exceeded shutdown timeout",
+			"service", l.name,
+			"timeout", timeout.String(),
+			"error", err.Error(),
+			"goroutines", goroutineDump(l.name),
+		)
+		err = fmt.Errorf("service %s: %w: %s", l.name, ErrShutdownTimeout, timeout)
+	} else if err != nil {
+		o.logger.Error("unexpected error occurred on service shutdown: ", "service", l.name, "error", err.Error())
+		err = fmt.Errorf("service %s: stop: %w", l.name, err)
+	}
+
+	if err != nil {
+		s.addErr(err)
+	}
+	o.emit(EventStopped, l.name, stoppingAt, err)
+}
+
+// stop calls Stop of the service and waits for Start of ServiceV2 to return, both are limited by the context
+func (o *Orchestrator) stop(ctx context.Context, l *lifecycle, exited <-chan struct{}) error {
+	errCh := make(chan error, 1)
+	go withServiceLabel(ctx, l.name, func(ctx context.Context) {
+		errCh <- l.runner.Stop(ctx)
+	})
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Start of Service is not required to return after Stop
+	if _, ok := l.runner.(serviceAdapter); ok {
+		return nil
+	}
+	select {
+	case <-exited:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("start has not returned: %w", ctx.Err())
+	}
+}
+
+// run calls Start of the service and applies the restart and exit policies if the service exits before shutdown
+func (o *Orchestrator) run(s *session, runCtx context.Context, l *lifecycle, exited chan<- struct{}) {
+	defer close(exited)
+
+	r := &restarts{backoff: l.backoff, max: l.maxRestarts, window: l.restartWindow}
+	for {
+		startedAt := time.Now()
+		ready := o.readyFunc(l, startedAt)
+		o.emit(EventStarting, l.name, time.Time{}, nil)
+
+		err := l.runner.Start(runCtx, ready)
+		if s.ctx.Err() != nil {
+			// shutdown has begun, the result does not matter
+			return
+		}
+		if err != nil {
+			o.emit(EventFailed, l.name, time.Time{}, err)
+		}
+
+		if !l.shouldRestart(err) {
+			if err != nil {
+				o.fail(s, l, err)
+				return
+			}
+			o.applyExitPolicy(s, l, ready)
+			return
+		}
+
+		delay, ok := r.next(time.Since(startedAt))
+		if !ok {
+			if err == nil {
+				err = errors.New("service has exited")
+			}
+			o.fail(s, l, fmt.Errorf("restart limit %d within %s is reached: %w", l.maxRestarts, l.restartWindow, err))
+			return
+		}
+
+		args := []any{"service", l.name, "delay", delay.String()}
+		if err != nil {
+			o.logger.Error("service has failed, restarting", append(args, "error", err.Error())...)
+		} else {
+			o.logger.Info("service has exited, restarting", args...)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// shouldRestart reports whether the service must be restarted after Start has returned the error
+func (l *lifecycle) shouldRestart(err error) bool {
+	switch {
+	case l.restartPolicy == RestartAlways:
+		return true
+	case err != nil:
+		return l.restartPolicy == RestartOnFailure
+	default:
+		return l.exitPolicy == ExitPolicyRestart
+	}
+}
+
+// fail stops the orchestrator if the service is critical, otherwise the failure is only logged and reported
+func (o *Orchestrator) fail(s *session, l *lifecycle, err error) {
+	if l.nonCritical {
+		o.logger.Error("non-critical service has failed", "service", l.name, "error", err.Error())
+		o.mu.Lock()
+		l.failure = err
+		o.mu.Unlock()
+		return
+	}
+	err = fmt.Errorf("service %s: %w", l.name, err)
+	// the error which has caused shutdown is returned first, the others are collected
+	select {
+	case s.errCh <- err:
+	case <-s.ctx.Done():
+		s.addErr(err)
+	}
+}
+
+// applyExitPolicy applies the exit policy after Start has returned nil
+func (o *Orchestrator) applyExitPolicy(s *session, l *lifecycle, ready func()) {
+	if l.exitPolicy == ExitPolicyStopAll {
+		select {
+		case s.exitCh <- l.name:
+		case <-s.ctx.Done():
+		}
+		return
+	}
+	o.logger.Info("service has exited", "service", l.name)
+	// the finished service must not block its dependents
+	ready()
+}
+
+// notReady returns names of services which are not ready yet
+func notReady(ls []*lifecycle) []string {
+	var names []string
+	for _, l := range ls {
+		if !l.isReady() && !l.nonCritical {
+			names = append(names, l.name)
+		}
+	}
+	return names
+}
+
+// waitAll waits until the channel of every lifecycle is closed, false is returned if the context is done earlier
+func waitAll(ctx context.Context, ls []*lifecycle, ch func(l *lifecycle) <-chan struct{}) bool {
+	for _, l := range ls {
+		select {
+		case <-ch(l):
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
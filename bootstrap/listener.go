@@ -0,0 +1,243 @@
+package bootstrap
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Environment variables of the socket activation protocol used by systemd
+const (
+	envListenFDs     = "LISTEN_FDS"
+	envListenPID     = "LISTEN_PID"
+	envListenFDNames = "LISTEN_FDNAMES"
+	// listenFDsStart is the first inherited file descriptor, SD_LISTEN_FDS_START
+	listenFDsStart = 3
+)
+
+// listeners keeps inherited listeners and listeners created by Listen, so they could be passed to the new process
+var listeners = &listenerRegistry{}
+
+type inheritedListener struct {
+	name     string
+	listener net.Listener
+}
+
+type listenerRegistry struct {
+	once      sync.Once
+	mu        sync.Mutex
+	inherited []inheritedListener
+	active    []*trackedListener
+	err       error
+}
+
+// Listen returns the inherited listener matching the network and the address, or creates a new one.
+//
+// Listeners are inherited using the systemd socket activation protocol (LISTEN_FDS, LISTEN_PID and LISTEN_FDNAMES
+// environment variables), so it works both with systemd socket units and with Orchestrator.Upgrade.
+// The listener matches if its name is "network:addr" or it is bound to the same address.
+// Listeners returned by Listen are passed to the new process on upgrade until they are closed
+func Listen(network, addr string) (net.Listener, error) {
+	return listeners.listen(network, addr)
+}
+
+func (r *listenerRegistry) listen(network, addr string) (net.Listener, error) {
+	r.inherit()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	name := network + ":" + addr
+	ln := r.take(func(l inheritedListener) bool { return l.name == name })
+	if ln == nil {
+		ln = r.take(func(l inheritedListener) bool { return addrMatches(network, addr, l.listener.Addr()) })
+	}
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen(network, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	tl := &trackedListener{Listener: ln, name: name, registry: r}
+	r.active = append(r.active, tl)
+	return tl, nil
+}
+
+func (r *listenerRegistry) inherit() {
+	r.once.Do(func() {
+		r.inherited, r.err = inheritListeners()
+	})
+}
+
+// closeUnclaimed closes inherited listeners which are not claimed by Listen and returns their names,
+// so their file descriptors are not leaked. Listen creates new listeners afterwards
+func (r *listenerRegistry) closeUnclaimed() []string {
+	r.inherit()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.inherited))
+	for _, l := range r.inherited {
+		_ = l.listener.Close()
+		names = append(names, l.name)
+	}
+	r.inherited = nil
+	return names
+}
+
+// take removes the first inherited listener satisfying the predicate and returns it
+func (r *listenerRegistry) take(match func(l inheritedListener) bool) net.Listener {
+	for i, l := range r.inherited {
+		if match(l) {
+			r.inherited = append(r.inherited[:i], r.inherited[i+1:]...)
+			return l.listener
+		}
+	}
+	return nil
+}
+
+// files returns duplicates of file descriptors of the active listeners along with their names
+func (r *listenerRegistry) files() ([]*os.File, []string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := make([]*os.File, 0, len(r.active))
+	names := make([]string, 0, len(r.active))
+	for _, l := range r.active {
+		filer, ok := l.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("listener %s does not support file descriptor passing", l.name)
+		}
+		f, err := filer.File()
+		if err != nil {
+			closeFiles(files)
+			return nil, nil, fmt.Errorf("cannot get file of listener %s: %w", l.name, err)
+		}
+		files = append(files, f)
+		names = append(names, l.name)
+	}
+	return files, names, nil
+}
+
+func (r *listenerRegistry) remove(l *trackedListener) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, active := range r.active {
+		if active == l {
+			r.active = append(r.active[:i], r.active[i+1:]...)
+			return
+		}
+	}
+}
+
+// trackedListener is removed from the registry once closed
+type trackedListener struct {
+	net.Listener
+	name      string
+	registry  *listenerRegistry
+	closeOnce sync.Once
+}
+
+func (l *trackedListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.registry.remove(l)
+	})
+	return l.Listener.Close()
+}
+
+// inheritListeners creates listeners from the inherited file descriptors. LISTEN_PID is optional since
+// the parent process is not able to know the PID of the child before it is started.
+// The environment variables are unset, so they are not inherited by child processes
+func inheritListeners() ([]inheritedListener, error) {
+	fdsValue := os.Getenv(envListenFDs)
+	if fdsValue == "" {
+		return nil, nil
+	}
+	defer func() {
+		_ = os.Unsetenv(envListenFDs)
+		_ = os.Unsetenv(envListenPID)
+		_ = os.Unsetenv(envListenFDNames)
+	}()
+
+	if pid := os.Getenv(envListenPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fdsValue)
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid %s value %q", envListenFDs, fdsValue)
+	}
+
+	var names []string
+	if v := os.Getenv(envListenFDNames); v != "" {
+		for _, name := range strings.Split(v, ":") {
+			names = append(names, decodeName(name))
+		}
+	}
+
+	result := make([]inheritedListener, 0, n)
+	for i := 0; i < n; i++ {
+		name := "unknown"
+		if i < len(names) {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		// FileListener duplicates the descriptor
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot inherit listener %s: %w", name, err)
+		}
+		result = append(result, inheritedListener{name: name, listener: ln})
+	}
+	return result, nil
+}
+
+// addrMatches reports whether the listener address is the one requested.
+// Unspecified IP addresses match each other, e.g. ":8080" and "[::]:8080"
+func addrMatches(network, addr string, actual net.Addr) bool {
+	if !strings.HasPrefix(network, "tcp") {
+		return actual.Network() == network && actual.String() == addr
+	}
+	requested, err := net.ResolveTCPAddr(network, addr)
+	if err != nil || requested.Port == 0 {
+		return false
+	}
+	tcpAddr, ok := actual.(*net.TCPAddr)
+	if !ok || tcpAddr.Port != requested.Port {
+		return false
+	}
+	if requested.IP == nil || requested.IP.IsUnspecified() {
+		return tcpAddr.IP == nil || tcpAddr.IP.IsUnspecified()
+	}
+	return requested.IP.Equal(tcpAddr.IP)
+}
+
+// names are separated by colons in LISTEN_FDNAMES, so colons of addresses are escaped
+var (
+	nameEncoder = strings.NewReplacer("%", "%25", ":", "%3A")
+	nameDecoder = strings.NewReplacer("%3A", ":", "%25", "%")
+)
+
+func encodeName(name string) string {
+	return nameEncoder.Replace(name)
+}
+
+func decodeName(name string) string {
+	return nameDecoder.Replace(name)
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
@@ -3,14 +3,23 @@ package bootstrap
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
-var ErrNoRegisteredServices = errors.New("orchestrator has no registered services")
+var (
+	ErrNoRegisteredServices = errors.New("orchestrator has no registered services")
+	ErrStartupTimeout       = errors.New("services are not ready within startup timeout")
+	ErrShutdownTimeout      = errors.New("service has not stopped within shutdown timeout")
+)
+
+// DefaultForceExitCode is the exit code used on forced exit unless WithForceExitCode is specified
+const DefaultForceExitCode = 1
 
 type option func(o *options)
 
@@ -18,6 +27,13 @@ type options struct {
 	signals         []os.Signal
 	shutDownTimeout time.Duration
 	logger          Logger
+	drainDelay      time.Duration
+	healthTimeout   time.Duration
+	startupTimeout  time.Duration
+	forceExitCode   int
+	exit            func(code int)
+	upgradeSignals  []os.Signal
+	upgradeTimeout  time.Duration
 }
 
 // WithStopSignals allows to specify signals which are considered as stop signals by Orchestrator.
@@ -49,20 +65,85 @@ func WithLogger(logger Logger) option {
 	}
 }
 
+// WithDrainDelay sets the delay between the moment readiness is reported as failed and the moment services
+// are stopped, so load balancers are able to remove the instance first. There is no delay by default
+func WithDrainDelay(d time.Duration) option {
+	return func(o *options) {
+		if d > 0 {
+			o.drainDelay = d
+		}
+	}
+}
+
+// WithHealthCheckTimeout sets the timeout of liveness checks. There is no default timeout
+func WithHealthCheckTimeout(t time.Duration) option {
+	return func(o *options) {
+		if t > 0 {
+			o.healthTimeout = t
+		}
+	}
+}
+
+// WithStartupTimeout sets the timeout for all services to become ready, Serve fails if it is exceeded.
+// There is no default timeout
+func WithStartupTimeout(t time.Duration) option {
+	return func(o *options) {
+		if t > 0 {
+			o.startupTimeout = t
+		}
+	}
+}
+
+// WithForceExitCode sets the exit code used when a stop signal is received again during shutdown.
+// DefaultForceExitCode is used by default
+func WithForceExitCode(code int) option {
+	return func(o *options) {
+		o.forceExitCode = code
+	}
+}
+
+// WithExitFunc replaces os.Exit called on forced exit, e.g. in order to flush logs before exiting
+func WithExitFunc(exit func(code int)) option {
+	return func(o *options) {
+		if exit != nil {
+			o.exit = exit
+		}
+	}
+}
+
 // Orchestrator helps to automate application services startup and graceful shutdown
 type Orchestrator struct {
 	logger          Logger
-	services        []Service
+	entries         []*entry
 	stopCh          chan os.Signal
+	stopReqCh       chan struct{}
 	signals         []os.Signal
 	shutDownTimeout time.Duration
+	drainDelay      time.Duration
+	startupTimeout  time.Duration
+	forceExitCode   int
+	exit            func(code int)
+	upgradeSignals  []os.Signal
+	upgradeTimeout  time.Duration
+
+	healthCheckTimeout time.Duration
+	// mu guards the fields below
+	mu           sync.Mutex
+	checks       []namedCheck
+	current      []*lifecycle
+	shuttingDown bool
+	handlers     []EventHandler
+	upgrading    bool
 }
 
 // NewOrchestrator builds new Orchestrator
 func NewOrchestrator(opts ...option) *Orchestrator {
 	o := options{
-		signals: []os.Signal{syscall.SIGINT, syscall.SIGTERM},
-		logger:  NewNoopLogger(),
+		signals:        []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		logger:         NewNoopLogger(),
+		forceExitCode:  DefaultForceExitCode,
+		exit:           os.Exit,
+		upgradeTimeout: DefaultUpgradeTimeout,
 	}
 
 	for _, opt := range opts {
@@ -72,87 +153,190 @@ func NewOrchestrator(opts ...option) *Orchestrator {
 	return &Orchestrator{
 		logger:          o.logger,
 		stopCh:          make(chan os.Signal, 1),
+		stopReqCh:       make(chan struct{}, 1),
 		signals:         o.signals,
 		shutDownTimeout: o.shutDownTimeout,
-	}
-}
+		drainDelay:      o.drainDelay,
+		startupTimeout:  o.startupTimeout,
+		forceExitCode:   o.forceExitCode,
+		exit:            o.exit,
+		upgradeSignals:  o.upgradeSignals,
+		upgradeTimeout:  o.upgradeTimeout,
 
-// Register registers Service for further serving
-func (o *Orchestrator) Register(svc Service) {
-	o.services = append(o.services, svc)
+		healthCheckTimeout: o.healthTimeout,
+	}
 }
 
 // Serve begins services startup and schedules further graceful shutdown procedures. Function behavior is blocking, any
 // stop signal sent begins graceful shutdown procedure
-func (o *Orchestrator) Serve() (err error) {
+func (o *Orchestrator) Serve() error {
+	return o.Run(context.Background())
+}
+
+// Run is the same as Serve, but cancellation of the context begins graceful shutdown procedure as well.
+// The returned error joins the error which has caused shutdown (if any) with errors occurred on shutdown
+func (o *Orchestrator) Run(ctx context.Context) error {
 	// verify at least one service is present
-	if len(o.services) == 0 {
+	if len(o.entries) == 0 {
 		return ErrNoRegisteredServices
 	}
+	if err := o.validate(); err != nil {
+		return err
+	}
 
-	errCh := make(chan error)
 	signal.Notify(o.stopCh, o.signals...)
 	// stop notifying channel after exit since no listeners will be present
 	defer signal.Stop(o.stopCh)
 
-	o.logger.Info("services are registered", "numberOfServices", len(o.services))
+	o.logger.Info("services are registered", "numberOfServices", len(o.entries))
+
+	stopCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &session{
+		ctx:    stopCtx,
+		errCh:  make(chan error),
+		exitCh: make(chan string),
+	}
 
-	var wg sync.WaitGroup
-	ctx, cancel := context.WithCancel(context.Background())
+	lifecycles := o.lifecycles()
+	o.setLifecycles(lifecycles)
+	defer o.setLifecycles(nil)
 
-	for i := range o.services {
-		wg.Add(1)
-		go o.serveLifecycle(ctx, o.services[i], &wg, errCh)
+	for _, l := range lifecycles {
+		s.wg.Add(1)
+		go o.serveLifecycle(s, l)
 	}
 
-	select {
-	// first startup error is assigned to return result
-	case err = <-errCh:
-		o.logger.Error("stopping services because of error: ", "error", err.Error())
-	case sig := <-o.stopCh:
-		o.logger.Info("stopping the services...", "signal", sig.String())
+	var upgradeCh chan os.Signal
+	if len(o.upgradeSignals) > 0 {
+		upgradeCh = make(chan os.Signal, 1)
+		signal.Notify(upgradeCh, o.upgradeSignals...)
+		defer signal.Stop(upgradeCh)
+	}
+	go o.completeStartup(s, lifecycles)
+
+	var startupTimeout <-chan time.Time
+	if o.startupTimeout > 0 {
+		timer := time.NewTimer(o.startupTimeout)
+		defer timer.Stop()
+		startupTimeout = timer.C
+	}
+
+	var err error
+	for stop := false; !stop; {
+		select {
+		// first startup error is the cause of shutdown
+		case err = <-s.errCh:
+			o.logger.Error("stopping services because of error: ", "error", err.Error())
+			stop = true
+		case name := <-s.exitCh:
+			o.logger.Info("stopping the services since the service has exited", "service", name)
+			stop = true
+		case sig := <-o.stopCh:
+			o.logger.Info("stopping the services...", "signal", sig.String())
+			stop = true
+		case <-o.stopReqCh:
+			o.logger.Info("stopping the services...", "signal", os.Interrupt.String())
+			stop = true
+		case <-ctx.Done():
+			o.logger.Info("stopping the services since the context is done", "error", ctx.Err().Error())
+			stop = true
+		case sig := <-upgradeCh:
+			o.logger.Info("upgrading the process...", "signal", sig.String())
+			// the current process keeps serving until the new one is ready, shutdown is begun by Upgrade
+			go func() {
+				_ = o.Upgrade()
+			}()
+		case <-startupTimeout:
+			if names := notReady(lifecycles); len(names) > 0 {
+				err = fmt.Errorf("%w: %s", ErrStartupTimeout, strings.Join(names, ", "))
+				o.logger.Error("stopping services because of error: ", "error", err.Error())
+				stop = true
+			}
+		}
+	}
+
+	o.mu.Lock()
+	o.shuttingDown = true
+	o.mu.Unlock()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go o.watchForcedExit(lifecycles, stopped)
+
+	if o.drainDelay > 0 {
+		o.logger.Info("readiness is failed, waiting before stopping the services", "drainDelay", o.drainDelay.String())
+		time.Sleep(o.drainDelay)
 	}
 
 	cancel()
 
 	o.logger.Info("waiting for services to be stopped")
-	wg.Wait()
+	s.wg.Wait()
 
-	return err
+	if len(s.errs) == 0 {
+		return err
+	}
+	return errors.Join(append([]error{err}, s.errs...)...)
 }
 
-// Stop sends stop signal, so starting graceful shutdown procedure
+// Stop sends stop signal, so starting graceful shutdown procedure. It is safe to call Stop multiple times
 func (o *Orchestrator) Stop() {
-	o.stopCh <- os.Interrupt
+	select {
+	case o.stopReqCh <- struct{}{}:
+	default:
+		// the stop signal is pending already
+	}
 }
 
-func (o *Orchestrator) serveLifecycle(ctx context.Context, svc Service, wg *sync.WaitGroup, errCh chan error) {
-	defer wg.Done()
+// completeStartup closes inherited listeners which are not claimed by services and reports readiness
+// to the parent process once every critical service is ready
+func (o *Orchestrator) completeStartup(s *session, ls []*lifecycle) {
+	for _, l := range ls {
+		if l.nonCritical {
+			continue
+		}
+		select {
+		case <-l.started:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+	for _, name := range listeners.closeUnclaimed() {
+		o.logger.Info("inherited listener is not used, closing", "listener", name)
+	}
+	if err := notifyParent(); err != nil {
+		o.logger.Error("cannot notify parent process about readiness", "error", err.Error())
+	}
+}
 
-	go func() {
-		if err := svc.Start(); err != nil {
-			// main error channel accepts only first error, so if error has been already passed by other service,
-			// just quit because of canceled context
-			select {
-			case errCh <- err:
-			case <-ctx.Done():
+// watchForcedExit exits the process if a stop signal is received again before shutdown is finished.
+// Stacks of services which are still stopping are logged before exiting
+func (o *Orchestrator) watchForcedExit(ls []*lifecycle, stopped <-chan struct{}) {
+	select {
+	case sig := <-o.stopCh:
+		o.logger.Error("stop signal is received during shutdown, exiting", "signal", sig.String(), "exitCode", o.forceExitCode)
+		for _, l := range ls {
+			if l.stopping.Load() && !isClosed(l.stopped) {
+				o.logger.Error("service is still stopping", "service", l.name, "goroutines", goroutineDump(l.name))
 			}
 		}
-	}()
-
-	<-ctx.Done()
-
-	stopCtx, stopCancel := o.shutdownContext()
-	defer stopCancel()
+		o.exit(o.forceExitCode)
+	case <-stopped:
+	}
+}
 
-	if err := svc.Stop(stopCtx); err != nil {
-		o.logger.Error("unexpected error occurred on service shutdown: ", err)
+// stopTimeout returns the shutdown timeout of the service, zero means no timeout
+func (o *Orchestrator) stopTimeout(l *lifecycle) time.Duration {
+	if l.stopTimeout > 0 {
+		return l.stopTimeout
 	}
+	return o.shutDownTimeout
 }
 
-func (o *Orchestrator) shutdownContext() (context.Context, context.CancelFunc) {
-	if o.shutDownTimeout > 0 {
-		return context.WithTimeout(context.Background(), o.shutDownTimeout)
+func (o *Orchestrator) shutdownContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
 	}
 	return context.WithCancel(context.Background())
 }
@@ -0,0 +1,137 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDuplicateService   = errors.New("service with the same name is already registered")
+	ErrDependencyCycle    = errors.New("service dependency cycle detected")
+	ErrUnknownDependency  = errors.New("service depends on unregistered service")
+	ErrInvalidServiceName = errors.New("service name must not be empty")
+)
+
+type serviceOption func(e *entry)
+
+// DependsOn declares services which must be started before the service and stopped after it.
+// Dependencies may be registered later, but they must be registered before Serve is called
+func DependsOn(names ...string) serviceOption {
+	return func(e *entry) {
+		e.deps = append(e.deps, names...)
+	}
+}
+
+// WithServiceStopTimeout sets the shutdown timeout of the particular service, it overrides WithShutdownTimeout
+func WithServiceStopTimeout(t time.Duration) serviceOption {
+	return func(e *entry) {
+		if t > 0 {
+			e.stopTimeout = t
+		}
+	}
+}
+
+// WithExitPolicy sets the policy applied when Start of the service returns nil before shutdown begins.
+// ExitPolicyIgnore is used by default
+func WithExitPolicy(p ExitPolicy) serviceOption {
+	return func(e *entry) {
+		e.exitPolicy = p
+	}
+}
+
+// entry is a registered service along with its settings
+type entry struct {
+	name string
+	// svc is the registered service, it is used to check optional interfaces such as HealthChecker
+	svc any
+	// runner controls the service lifecycle, Service is adapted to ServiceV2
+	runner      ServiceV2
+	deps        []string
+	stopTimeout time.Duration
+	exitPolicy  ExitPolicy
+
+	restartPolicy RestartPolicy
+	backoff       Backoff
+	maxRestarts   int
+	restartWindow time.Duration
+	nonCritical   bool
+}
+
+// Register registers Service for further serving. The service has no dependencies and gets a generated name
+func (o *Orchestrator) Register(svc Service) {
+	name := fmt.Sprintf("service-%d", len(o.entries)+1)
+	for o.entry(name) != nil {
+		name = name + "'"
+	}
+	o.entries = append(o.entries, &entry{name: name, svc: svc, runner: serviceAdapter{svc}, backoff: DefaultBackoff})
+}
+
+// RegisterNamed registers Service under the given name. The name is used to declare dependencies and in logs.
+// Services are started in the dependency order and stopped in the reverse one, independent services are started
+// and stopped concurrently. An error is returned if the name is already taken or the dependencies form a cycle
+func (o *Orchestrator) RegisterNamed(name string, svc Service, opts ...serviceOption) error {
+	return o.register(&entry{name: name, svc: svc, runner: serviceAdapter{svc}, backoff: DefaultBackoff}, opts)
+}
+
+// RegisterV2 registers ServiceV2 under the given name, see RegisterNamed for details
+func (o *Orchestrator) RegisterV2(name string, svc ServiceV2, opts ...serviceOption) error {
+	return o.register(&entry{name: name, svc: svc, runner: svc, backoff: DefaultBackoff}, opts)
+}
+
+func (o *Orchestrator) register(e *entry, opts []serviceOption) error {
+	name := e.name
+	if name == "" {
+		return ErrInvalidServiceName
+	}
+	if o.entry(name) != nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateService, name)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if path := o.findCycle(e, e.name, nil); path != nil {
+		return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(path, " -> "))
+	}
+	o.entries = append(o.entries, e)
+	return nil
+}
+
+func (o *Orchestrator) entry(name string) *entry {
+	for _, e := range o.entries {
+		if e.name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// findCycle looks for the path from e to the target through the dependencies,
+// the graph without the new entry is acyclic, so any cycle goes through it
+func (o *Orchestrator) findCycle(e *entry, target string, path []string) []string {
+	path = append(path, e.name)
+	for _, dep := range e.deps {
+		if dep == target {
+			return append(path, dep)
+		}
+		if d := o.entry(dep); d != nil {
+			if found := o.findCycle(d, target, path); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// validate verifies that every dependency is registered
+func (o *Orchestrator) validate() error {
+	for _, e := range o.entries {
+		for _, dep := range e.deps {
+			if o.entry(dep) == nil {
+				return fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, e.name, dep)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package bootstrap
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RestartPolicy defines whether the service is restarted after Start returns
+type RestartPolicy int
+
+const (
+	// RestartNever does not restart the service, the exit policy is applied on clean exit
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts the service if Start returns an error
+	RestartOnFailure
+	// RestartAlways restarts the service whenever Start returns before shutdown
+	RestartAlways
+)
+
+// String returns the name of the policy
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartNever:
+		return "never"
+	case RestartOnFailure:
+		return "onFailure"
+	case RestartAlways:
+		return "always"
+	default:
+		return "unknown"
+	}
+}
+
+// Backoff configures delays between restarts
+type Backoff struct {
+	// Initial is the delay before the first restart
+	Initial time.Duration
+	// Max is the upper bound of the delay
+	Max time.Duration
+	// Multiplier is applied to the delay after each restart
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomized, e.g. 0.2 means ±20%
+	Jitter float64
+}
+
+// DefaultBackoff is used unless WithRestartBackoff is specified
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        time.Minute,
+	Multiplier: 2,
+	Jitter:     0.2,
+}
+
+// withDefaults fills zero and invalid fields from DefaultBackoff, so a partially filled backoff never gives
+// zero delays
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = DefaultBackoff.Max
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+	return b
+}
+
+// delay returns the delay before the restart with the given zero based number
+func (b Backoff) delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	for i := 0; i < attempt && d < float64(b.Max); i++ {
+		d *= b.Multiplier
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// WithRestartPolicy sets the restart policy of the service. RestartNever is used by default
+func WithRestartPolicy(p RestartPolicy) serviceOption {
+	return func(e *entry) {
+		e.restartPolicy = p
+	}
+}
+
+// WithRestartBackoff sets delays between restarts of the service. DefaultBackoff is used by default,
+// zero or invalid fields (Initial, Max, Multiplier less than 1) are taken from it as well
+func WithRestartBackoff(b Backoff) serviceOption {
+	return func(e *entry) {
+		e.backoff = b.withDefaults()
+	}
+}
+
+// WithMaxRestarts limits the number of restarts within the sliding time window. Once the limit is reached
+// the service is considered failed. There is no limit by default
+func WithMaxRestarts(n int, window time.Duration) serviceOption {
+	return func(e *entry) {
+		if n > 0 && window > 0 {
+			e.maxRestarts = n
+			e.restartWindow = window
+		}
+	}
+}
+
+// NonCritical marks the service as non-critical: its failure is logged and reported by health checks,
+// but other services keep running. Services are critical by default, the failure of such a service
+// stops the orchestrator
+func NonCritical() serviceOption {
+	return func(e *entry) {
+		e.nonCritical = true
+	}
+}
+
+// restarts tracks restarts of the service within the window
+type restarts struct {
+	backoff Backoff
+	max     int
+	window  time.Duration
+	attempt int
+	times   []time.Time
+}
+
+// next returns the delay before the next restart, false is returned if the limit is reached.
+// The backoff is reset if the service has been running longer than the maximum delay
+func (r *restarts) next(ranFor time.Duration) (time.Duration, bool) {
+	now := time.Now()
+	if r.max > 0 {
+		kept := r.times[:0]
+		for _, t := range r.times {
+			if now.Sub(t) < r.window {
+				kept = append(kept, t)
+			}
+		}
+		r.times = kept
+		if len(r.times) >= r.max {
+			return 0, false
+		}
+		r.times = append(r.times, now)
+	}
+	if ranFor >= r.backoff.Max {
+		r.attempt = 0
+	}
+	d := r.backoff.delay(r.attempt)
+	r.attempt++
+	return d, true
+}
@@ -0,0 +1,190 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+var fastBackoff = bootstrap.Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2, Jitter: 0.2}
+
+type logRecorder struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *logRecorder) Info(msg string, _ ...any) {
+	l.add(msg)
+}
+
+func (l *logRecorder) Error(msg string, _ ...any) {
+	l.add(msg)
+}
+
+func (l *logRecorder) add(msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, msg)
+}
+
+func (l *logRecorder) count(msg string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for _, m := range l.messages {
+		if m == msg {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOrchestrator_RestartOnFailure(t *testing.T) {
+	var starts atomic.Int32
+	logger := &logRecorder{}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(logger))
+
+	flaky := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		if starts.Add(1) < 3 {
+			return errors.New("connection refused")
+		}
+		ready()
+		<-ctx.Done()
+		return nil
+	}}
+	mustRegister(t, orc.RegisterV2(
+		"pusher",
+		flaky,
+		bootstrap.WithRestartPolicy(bootstrap.RestartOnFailure),
+		bootstrap.WithRestartBackoff(fastBackoff),
+	))
+
+	serveAndStop(t, orc, func() bool { return starts.Load() == 3 })
+
+	if n := logger.count("service has failed, restarting"); n != 2 {
+		t.Errorf("expected 2 restarts to be logged, got %d", n)
+	}
+}
+
+func TestOrchestrator_RestartPartialBackoff(t *testing.T) {
+	var starts atomic.Int32
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+
+	exiting := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		starts.Add(1)
+		return nil
+	}}
+	mustRegister(t, orc.RegisterV2(
+		"worker",
+		exiting,
+		bootstrap.WithRestartPolicy(bootstrap.RestartAlways),
+		// the initial delay and the multiplier are taken from DefaultBackoff
+		bootstrap.WithRestartBackoff(bootstrap.Backoff{Max: time.Minute}),
+	))
+
+	serveAndStop(t, orc, func() bool {
+		if starts.Load() == 0 {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+		return true
+	})
+
+	if n := starts.Load(); n != 1 {
+		t.Errorf("expected the service to wait for the default initial delay, got %d starts", n)
+	}
+}
+
+func TestOrchestrator_RestartEmitsStarted(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []bootstrap.EventType
+		starts atomic.Int32
+	)
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	orc.Subscribe(func(e bootstrap.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e.Type)
+	})
+
+	crashing := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		ready()
+		ready()
+		if starts.Add(1) < 3 {
+			return errors.New("connection reset")
+		}
+		<-ctx.Done()
+		return nil
+	}}
+	mustRegister(t, orc.RegisterV2(
+		"consumer",
+		crashing,
+		bootstrap.WithRestartPolicy(bootstrap.RestartOnFailure),
+		bootstrap.WithRestartBackoff(fastBackoff),
+	))
+
+	serveAndStop(t, orc, func() bool { return starts.Load() == 3 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []bootstrap.EventType{
+		bootstrap.EventStarting, bootstrap.EventStarted, bootstrap.EventFailed,
+		bootstrap.EventStarting, bootstrap.EventStarted, bootstrap.EventFailed,
+		bootstrap.EventStarting, bootstrap.EventStarted,
+		bootstrap.EventStopping, bootstrap.EventStopped,
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+	}
+}
+
+func TestOrchestrator_MaxRestarts(t *testing.T) {
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	failing := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		return ErrFailedStartup
+	}}
+	mustRegister(t, orc.RegisterV2(
+		"failing",
+		failing,
+		bootstrap.WithRestartPolicy(bootstrap.RestartAlways),
+		bootstrap.WithRestartBackoff(fastBackoff),
+		bootstrap.WithMaxRestarts(3, time.Minute),
+	))
+
+	err := serveWithTimeout(t, orc)
+	if !errors.Is(err, ErrFailedStartup) || !strings.Contains(err.Error(), "restart limit 3 within 1m0s is reached") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestOrchestrator_NonCriticalFailure(t *testing.T) {
+	r := &recorder{}
+	logger := &logRecorder{}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(logger))
+
+	warmer := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		return errors.New("cache is unavailable")
+	}}
+	mustRegister(t, orc.RegisterV2("warmer", warmer, bootstrap.NonCritical()))
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(r, "http")))
+
+	serveAndStop(t, orc, func() bool {
+		if logger.count("non-critical service has failed") == 0 {
+			return false
+		}
+		report := orc.Readiness(context.Background())
+		return report.OK() && report.Checks["warmer"].Error == "cache is unavailable"
+	})
+}
@@ -0,0 +1,118 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+func TestOrchestrator_RunContextCancel(t *testing.T) {
+	r := &recorder{}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(r, "http")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- orc.Run(ctx)
+	}()
+
+	waitFor(t, func() bool { return r.index("start http") >= 0 })
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("failed to shutdown orchestrator within timeout")
+	}
+	if r.index("exit http") < 0 {
+		t.Error("expected the service to be stopped")
+	}
+}
+
+func TestOrchestrator_StopIsIdempotent(t *testing.T) {
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(&recorder{}, "http")))
+
+	done := make(chan struct{})
+	go func() {
+		orc.Stop()
+		orc.Stop()
+		orc.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("Stop must not block")
+	}
+	if err := serveWithTimeout(t, orc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestOrchestrator_Events(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []bootstrap.Event
+	)
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	orc.Subscribe(func(e bootstrap.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+
+	r := &recorder{}
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(r, "http")))
+	serveAndStop(t, orc, func() bool { return r.index("start http") >= 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []bootstrap.EventType{
+		bootstrap.EventStarting,
+		bootstrap.EventStarted,
+		bootstrap.EventStopping,
+		bootstrap.EventStopped,
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range events {
+		if e.Type != expected[i] || e.Service != "http" || e.Err != nil {
+			t.Errorf("unexpected event %d: %+v", i, e)
+		}
+	}
+}
+
+func TestOrchestrator_AggregatedError(t *testing.T) {
+	errStop := errors.New("cannot flush")
+
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	tracer := untilCancelled(&recorder{}, "tracer")
+	tracer.stop = func(ctx context.Context) error {
+		return errStop
+	}
+	failing := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		return ErrFailedStartup
+	}}
+	mustRegister(t, orc.RegisterV2("tracer", tracer))
+	mustRegister(t, orc.RegisterV2("consumer", failing, bootstrap.DependsOn("tracer")))
+
+	err := serveWithTimeout(t, orc)
+	if !errors.Is(err, ErrFailedStartup) || !errors.Is(err, errStop) {
+		t.Fatalf("expected both startup and shutdown errors, got %v", err)
+	}
+	expected := "service consumer: startup failed\nservice tracer: stop: cannot flush"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
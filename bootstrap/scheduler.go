@@ -0,0 +1,67 @@
+package bootstrap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Scheduler schedules periodic tasks, it is implemented by tickrx.Scheduler
+type Scheduler interface {
+	Add(interval time.Duration, task func(ctx context.Context))
+	Stop()
+}
+
+type scheduledTask struct {
+	interval time.Duration
+	task     func(ctx context.Context)
+}
+
+// SchedulerService is ServiceV2 adapting Scheduler. Tasks added to the service are scheduled when the service is
+// started, so they run only after the dependencies of the service are ready
+type SchedulerService struct {
+	scheduler Scheduler
+	mu        sync.Mutex
+	tasks     []scheduledTask
+}
+
+// NewSchedulerService creates a new SchedulerService
+func NewSchedulerService(s Scheduler) *SchedulerService {
+	return &SchedulerService{scheduler: s}
+}
+
+// Add adds the task which is scheduled once the service is started
+func (s *SchedulerService) Add(interval time.Duration, task func(ctx context.Context)) *SchedulerService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks = append(s.tasks, scheduledTask{interval: interval, task: task})
+	return s
+}
+
+// Start schedules the tasks and blocks until the service is stopped
+func (s *SchedulerService) Start(ctx context.Context, ready func()) error {
+	s.mu.Lock()
+	for _, t := range s.tasks {
+		s.scheduler.Add(t.interval, t.task)
+	}
+	s.mu.Unlock()
+
+	ready()
+	<-ctx.Done()
+	return nil
+}
+
+// Stop stops the scheduler and waits for running tasks to finish
+func (s *SchedulerService) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.scheduler.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -34,3 +34,75 @@ func (s *serviceFunc) Stop(ctx context.Context) error {
 func ServiceFunc(start StartFunc, stop StopFunc) *serviceFunc {
 	return &serviceFunc{start: start, stop: stop}
 }
+
+// ServiceV2 represents service for orchestration which reports its readiness explicitly.
+//
+// Start must call ready once the service is able to do its job, dependent services are started after that.
+// The context passed to Start is cancelled when the service must be stopped, right before Stop is called.
+// Unlike Service, the orchestrator waits for Start to return during shutdown (within the shutdown timeout)
+type ServiceV2 interface {
+	Start(ctx context.Context, ready func()) error
+	Stop(ctx context.Context) error
+}
+
+// ExitPolicy defines the behavior when Start returns nil before shutdown begins
+type ExitPolicy int
+
+const (
+	// ExitPolicyIgnore considers the service finished and ready, e.g. a one-off migration job
+	ExitPolicyIgnore ExitPolicy = iota
+	// ExitPolicyStopAll begins graceful shutdown of all services
+	ExitPolicyStopAll
+	// ExitPolicyRestart calls Start again
+	ExitPolicyRestart
+)
+
+// String returns the name of the policy
+func (p ExitPolicy) String() string {
+	switch p {
+	case ExitPolicyIgnore:
+		return "ignore"
+	case ExitPolicyStopAll:
+		return "stopAll"
+	case ExitPolicyRestart:
+		return "restart"
+	default:
+		return "unknown"
+	}
+}
+
+// serviceAdapter adapts Service to ServiceV2. The service is ready as soon as Start is called
+// unless it implements ReadyNotifier
+type serviceAdapter struct {
+	Service
+}
+
+func (s serviceAdapter) Start(ctx context.Context, ready func()) error {
+	if rn, ok := s.Service.(ReadyNotifier); ok {
+		go func() {
+			select {
+			case <-rn.Ready():
+				ready()
+			case <-ctx.Done():
+			}
+		}()
+	} else {
+		ready()
+	}
+	return s.Service.Start()
+}
+
+// RunFunc is ServiceV2 which runs the function until the context is cancelled, e.g. a queue consumer loop.
+// The service is ready as soon as it is started, Stop does nothing since cancellation of the context is enough
+type RunFunc func(ctx context.Context) error
+
+// Start calls f(ctx)
+func (f RunFunc) Start(ctx context.Context, ready func()) error {
+	ready()
+	return f(ctx)
+}
+
+// Stop does nothing, the context passed to Start is cancelled right before Stop is called
+func (f RunFunc) Stop(context.Context) error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package bootstrap_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+type serviceV2 struct {
+	start func(ctx context.Context, ready func()) error
+	stop  func(ctx context.Context) error
+}
+
+func (s *serviceV2) Start(ctx context.Context, ready func()) error {
+	return s.start(ctx, ready)
+}
+
+func (s *serviceV2) Stop(ctx context.Context) error {
+	if s.stop == nil {
+		return nil
+	}
+	return s.stop(ctx)
+}
+
+// untilCancelled is ready immediately and runs until the context is cancelled
+func untilCancelled(r *recorder, name string) *serviceV2 {
+	return &serviceV2{start: func(ctx context.Context, ready func()) error {
+		r.add("start " + name)
+		ready()
+		<-ctx.Done()
+		r.add("exit " + name)
+		return nil
+	}}
+}
+
+func TestOrchestrator_ServiceV2Order(t *testing.T) {
+	r := &recorder{}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+
+	slow := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		time.Sleep(50 * time.Millisecond)
+		r.add("start db")
+		ready()
+		<-ctx.Done()
+		r.add("exit db")
+		return nil
+	}}
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(r, "http"), bootstrap.DependsOn("db")))
+	mustRegister(t, orc.RegisterV2("db", slow))
+
+	serveAndStop(t, orc, func() bool { return r.index("start http") >= 0 })
+
+	assertBefore(t, r, "start db", "start http")
+	assertBefore(t, r, "exit http", "exit db")
+}
+
+func TestOrchestrator_ExitPolicy(t *testing.T) {
+	t.Run("ignore", func(t *testing.T) {
+		r := &recorder{}
+		orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+		migration := &serviceV2{start: func(ctx context.Context, ready func()) error {
+			r.add("start migration")
+			return nil
+		}}
+		// the service which has exited does not block its dependents
+		mustRegister(t, orc.RegisterV2("migration", migration))
+		mustRegister(t, orc.RegisterV2("http", untilCancelled(r, "http"), bootstrap.DependsOn("migration")))
+
+		serveAndStop(t, orc, func() bool { return r.index("start http") >= 0 })
+	})
+
+	t.Run("stop all", func(t *testing.T) {
+		r := &recorder{}
+		orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+		job := &serviceV2{start: func(ctx context.Context, ready func()) error {
+			ready()
+			return nil
+		}}
+		mustRegister(t, orc.RegisterV2(
+			"job",
+			job,
+			bootstrap.WithExitPolicy(bootstrap.ExitPolicyStopAll),
+			bootstrap.DependsOn("http"),
+		))
+		mustRegister(t, orc.RegisterV2("http", untilCancelled(r, "http")))
+
+		if err := serveWithTimeout(t, orc); err != nil {
+			t.Fatalf("expected clean shutdown, got %v", err)
+		}
+		if r.index("exit http") < 0 {
+			t.Error("expected other services to be stopped")
+		}
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		var starts atomic.Int32
+		orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+		worker := &serviceV2{start: func(ctx context.Context, ready func()) error {
+			ready()
+			starts.Add(1)
+			return nil
+		}}
+		mustRegister(t, orc.RegisterV2(
+			"worker",
+			worker,
+			bootstrap.WithExitPolicy(bootstrap.ExitPolicyRestart),
+			bootstrap.WithRestartBackoff(bootstrap.Backoff{Initial: time.Millisecond, Max: time.Millisecond}),
+		))
+
+		serveAndStop(t, orc, func() bool { return starts.Load() >= 3 })
+	})
+}
+
+func TestOrchestrator_StartupTimeout(t *testing.T) {
+	orc := bootstrap.NewOrchestrator(
+		bootstrap.WithLogger(bootstrap.NewNoopLogger()),
+		bootstrap.WithStartupTimeout(100*time.Millisecond),
+	)
+	stuck := &serviceV2{start: func(ctx context.Context, ready func()) error {
+		<-ctx.Done()
+		return nil
+	}}
+	mustRegister(t, orc.RegisterV2("stuck", stuck))
+	mustRegister(t, orc.RegisterV2("http", untilCancelled(&recorder{}, "http")))
+
+	err := serveWithTimeout(t, orc)
+	if !errors.Is(err, bootstrap.ErrStartupTimeout) {
+		t.Fatalf("expected startup timeout error, got %v", err)
+	}
+	if expected := "services are not ready within startup timeout: stuck"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func serveWithTimeout(t *testing.T, orc *bootstrap.Orchestrator) error {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- orc.Serve()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(resultWaitTimeout):
+		t.Fatal("serve has not returned within timeout")
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envReadyFD is the file descriptor the new process writes to once its services are ready
+const envReadyFD = "BOOTSTRAP_READY_FD"
+
+// DefaultUpgradeTimeout is used unless WithUpgradeTimeout is specified
+const DefaultUpgradeTimeout = time.Minute
+
+var (
+	ErrUpgradeInProgress = errors.New("upgrade is already in progress")
+	ErrUpgradeFailed     = errors.New("new process has not become ready")
+)
+
+// WithUpgradeSignals enables zero-downtime upgrades triggered by the given signals, e.g. syscall.SIGHUP or
+// syscall.SIGUSR2. See Orchestrator.Upgrade for details
+func WithUpgradeSignals(signals ...os.Signal) option {
+	return func(o *options) {
+		o.upgradeSignals = signals
+	}
+}
+
+// WithUpgradeTimeout sets the timeout for the new process to become ready. DefaultUpgradeTimeout is used by default
+func WithUpgradeTimeout(t time.Duration) option {
+	return func(o *options) {
+		if t > 0 {
+			o.upgradeTimeout = t
+		}
+	}
+}
+
+// Upgrade starts the new process from the same executable with the same arguments passing it listeners
+// created by Listen (including HTTPServer listeners), waits for the new process to become ready and begins
+// graceful shutdown of the current process. The new process picks up the listeners automatically,
+// so connections are not refused during the upgrade.
+//
+// If the new process fails or does not become ready within the upgrade timeout,
+// it is killed and the current process keeps running
+func (o *Orchestrator) Upgrade() error {
+	o.mu.Lock()
+	if o.upgrading {
+		o.mu.Unlock()
+		return ErrUpgradeInProgress
+	}
+	o.upgrading = true
+	o.mu.Unlock()
+
+	defer func() {
+		o.mu.Lock()
+		o.upgrading = false
+		o.mu.Unlock()
+	}()
+
+	if err := o.startNewProcess(); err != nil {
+		o.logger.Error("upgrade has failed", "error", err.Error())
+		return err
+	}
+	o.logger.Info("new process is ready, stopping the current one")
+	o.Stop()
+	return nil
+}
+
+func (o *Orchestrator) startNewProcess() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot find executable: %w", err)
+	}
+
+	files, names, err := listeners.files()
+	if err != nil {
+		return err
+	}
+	defer closeFiles(files)
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("cannot create ready pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// listeners go first as the socket activation protocol requires, the ready pipe follows them
+	cmd.ExtraFiles = append(files, readyW)
+	cmd.Env = append(
+		upgradeEnviron(),
+		envListenFDs+"="+strconv.Itoa(len(files)),
+		envListenFDNames+"="+encodeNames(names),
+		envReadyFD+"="+strconv.Itoa(listenFDsStart+len(files)),
+	)
+
+	err = cmd.Start()
+	// the write end belongs to the child now, so reading returns EOF once the child exits
+	_ = readyW.Close()
+	if err != nil {
+		return fmt.Errorf("cannot start new process: %w", err)
+	}
+	o.logger.Info("new process is started", "pid", cmd.Process.Pid, "listeners", len(files))
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	readyCh := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyR.Read(buf)
+		readyCh <- err
+	}()
+
+	timer := time.NewTimer(o.upgradeTimeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-readyCh:
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			err = errors.New("ready pipe is closed")
+		}
+	case <-timer.C:
+		err = fmt.Errorf("timeout %s", o.upgradeTimeout)
+	}
+
+	_ = cmd.Process.Kill()
+	if exitErr := <-exited; exitErr != nil {
+		err = fmt.Errorf("%v: %w", err, exitErr)
+	}
+	return fmt.Errorf("%w: %v", ErrUpgradeFailed, err)
+}
+
+func encodeNames(names []string) string {
+	encoded := make([]string, len(names))
+	for i, name := range names {
+		encoded[i] = encodeName(name)
+	}
+	return strings.Join(encoded, ":")
+}
+
+// upgradeEnviron returns the environment of the current process without the variables of the upgrade protocol
+func upgradeEnviron() []string {
+	env := os.Environ()
+	result := env[:0:0]
+	for _, kv := range env {
+		switch strings.SplitN(kv, "=", 2)[0] {
+		case envListenFDs, envListenPID, envListenFDNames, envReadyFD:
+		default:
+			result = append(result, kv)
+		}
+	}
+	return result
+}
+
+var notifyOnce sync.Once
+
+// notifyParent reports readiness to the parent process which has started the current one during upgrade
+func notifyParent() error {
+	var err error
+	notifyOnce.Do(func() {
+		value := os.Getenv(envReadyFD)
+		if value == "" {
+			return
+		}
+		_ = os.Unsetenv(envReadyFD)
+
+		fd, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			err = fmt.Errorf("invalid %s value %q", envReadyFD, value)
+			return
+		}
+		f := os.NewFile(uintptr(fd), "ready")
+		defer f.Close()
+		_, err = f.Write([]byte{1})
+	})
+	return err
+}
@@ -0,0 +1,243 @@
+package bootstrap_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/bootstrap"
+)
+
+const (
+	envUpgradeChild   = "BOOTSTRAP_TEST_UPGRADE_CHILD"
+	envUpgradeAddr    = "BOOTSTRAP_TEST_UPGRADE_ADDR"
+	envInheritedChild = "BOOTSTRAP_TEST_INHERITED_CHILD"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(envUpgradeChild) == "1" {
+		os.Exit(runUpgradedChild())
+	}
+	if os.Getenv(envInheritedChild) == "1" {
+		os.Exit(runInheritedChild())
+	}
+	os.Exit(m.Run())
+}
+
+// runInheritedChild claims the inherited listener bound to BOOTSTRAP_TEST_UPGRADE_ADDR only
+func runInheritedChild() int {
+	svc := bootstrap.RunFunc(func(ctx context.Context) error {
+		ln, err := bootstrap.Listen("tcp", os.Getenv(envUpgradeAddr))
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		<-ctx.Done()
+		return nil
+	})
+	orc := bootstrap.NewOrchestrator(bootstrap.WithStopSignals(syscall.SIGTERM))
+	if err := orc.RegisterV2("listener", svc); err != nil {
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := orc.Run(ctx); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// runUpgradedChild is the new process started by Upgrade, it serves on the inherited listener
+func runUpgradedChild() int {
+	srv, err := bootstrap.NewHTTPServer(&http.Server{
+		Addr: os.Getenv(envUpgradeAddr),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "child %d", os.Getpid())
+		}),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithStopSignals(syscall.SIGTERM))
+	if err = orc.RegisterV2("http", srv); err != nil {
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = orc.Run(ctx); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestOrchestrator_Upgrade(t *testing.T) {
+	// the address is requested explicitly, so the child finds the listener by its name
+	srv, err := bootstrap.NewHTTPServer(&http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("parent"))
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orc := bootstrap.NewOrchestrator(
+		bootstrap.WithLogger(bootstrap.NewNoopLogger()),
+		bootstrap.WithUpgradeSignals(syscall.SIGUSR2),
+		bootstrap.WithUpgradeTimeout(5*time.Second),
+	)
+	mustRegister(t, orc.RegisterV2("http", srv))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- orc.Run(context.Background())
+	}()
+
+	url := "http://" + srv.Addr().String()
+	waitFor(t, func() bool {
+		_, body := get(url)
+		return body == "parent"
+	})
+
+	t.Setenv(envUpgradeChild, "1")
+	t.Setenv(envUpgradeAddr, "127.0.0.1:0")
+	if err = sendSignal(syscall.SIGUSR2); err != nil {
+		t.Fatalf("failed to send signal to current process: %v", err)
+	}
+
+	select {
+	case err = <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(2 * resultWaitTimeout):
+		t.Fatal("the parent process has not stopped after upgrade")
+	}
+
+	// the listener is still open, it is served by the new process
+	status, body := get(url)
+	if status != http.StatusOK || !strings.HasPrefix(body, "child ") {
+		t.Fatalf("expected the child to serve requests on %s, got %d %q", url, status, body)
+	}
+	pid, _ := strconv.Atoi(strings.TrimPrefix(body, "child "))
+	if pid == 0 || pid == os.Getpid() {
+		t.Fatalf("unexpected child pid %q", body)
+	}
+	if err = syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		t.Errorf("cannot stop the child process: %v", err)
+	}
+}
+
+func TestOrchestrator_UpgradeFailure(t *testing.T) {
+	srv, err := bootstrap.NewHTTPServer(&http.Server{
+		Addr:    "127.0.0.1:0",
+		Handler: http.NotFoundHandler(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orc := bootstrap.NewOrchestrator(bootstrap.WithLogger(bootstrap.NewNoopLogger()))
+	mustRegister(t, orc.RegisterV2("http", srv))
+
+	// the child exits immediately since it is not able to bind the address
+	t.Setenv(envUpgradeChild, "1")
+	t.Setenv(envUpgradeAddr, "256.0.0.1:0")
+
+	serveAndStop(t, orc, func() bool {
+		if !orc.Readiness(context.Background()).OK() {
+			return false
+		}
+		if err := orc.Upgrade(); err == nil || !strings.Contains(err.Error(), bootstrap.ErrUpgradeFailed.Error()) {
+			t.Errorf("expected upgrade to fail, got %v", err)
+		}
+		// the current process keeps serving
+		status, _ := get("http://" + srv.Addr().String())
+		return status == http.StatusNotFound
+	})
+}
+
+func TestOrchestrator_ClosesUnclaimedListeners(t *testing.T) {
+	claimed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unclaimed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files []*os.File
+	for _, ln := range []net.Listener{claimed, unclaimed} {
+		f, fileErr := ln.(*net.TCPListener).File()
+		if fileErr != nil {
+			t.Fatal(fileErr)
+		}
+		files = append(files, f)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(),
+		envInheritedChild+"=1",
+		envUpgradeAddr+"="+claimed.Addr().String(),
+		"LISTEN_FDS=2",
+		"LISTEN_FDNAMES="+strings.Join([]string{
+			"tcp%3A" + strings.ReplaceAll(claimed.Addr().String(), ":", "%3A"),
+			"tcp%3A" + strings.ReplaceAll(unclaimed.Addr().String(), ":", "%3A"),
+		}, ":"),
+	)
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		_ = cmd.Wait()
+	}()
+
+	// the child holds the only remaining descriptors of the sockets
+	closeFiles(files)
+	_ = claimed.Close()
+	_ = unclaimed.Close()
+
+	waitFor(t, func() bool {
+		conn, dialErr := net.Dial("tcp", unclaimed.Addr().String())
+		if dialErr == nil {
+			_ = conn.Close()
+			return false
+		}
+		return true
+	})
+
+	conn, err := net.Dial("tcp", claimed.Addr().String())
+	if err != nil {
+		t.Fatalf("expected the claimed listener to be open: %v", err)
+	}
+	_ = conn.Close()
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+func TestListen_Inherited(t *testing.T) {
+	ln, err := bootstrap.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	if _, err = bootstrap.Listen("tcp", ln.Addr().String()); err == nil {
+		t.Error("expected the address to be in use")
+	}
+}
@@ -1,9 +1,5 @@
 package envx
 
-import (
-	"strings"
-)
-
 // SourceOption represents a functional option for configuring a source.
 type SourceOption func(*sourceConfig)
 
@@ -325,7 +321,7 @@ func (r *StandardResolver) getFilteredSources(labels []string) []labeledSource {
 
 	// Log warning if no sources matched
 	if len(filtered) == 0 {
-		r.logger.Warn("no sources matched labels", "["+strings.Join(labels, " ")+"]")
+		r.logger.Warn("no sources matched labels", "labels", labels)
 	}
 
 	return filtered
@@ -0,0 +1,118 @@
+package sqltx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoTransaction is returned when a hook is registered with the context which has no transaction
+var ErrNoTransaction = errors.New("sqltx: there is no transaction in the context")
+
+// Hook is a function called after the transaction is finished
+type Hook func(ctx context.Context) error
+
+// HookErrorHandler receives errors returned by hooks and recovered hook panics
+type HookErrorHandler func(ctx context.Context, err error)
+
+// WithHookErrorHandler sets the handler of hook errors. By default, the errors are logged as warnings
+func WithHookErrorHandler(h HookErrorHandler) WrapperOption {
+	return func(w *DefaultWrapper) {
+		w.hookErrorHandler = h
+	}
+}
+
+// AfterCommit registers the hook called after the transaction in the context is committed, e.g. in order to publish
+// an event or to invalidate a cache. Hooks registered within nested transactions are attached to the outermost one.
+// Hooks are called in the order of registration with the context passed to the outermost WithTransaction call.
+// Errors of hooks do not affect the result of WithTransaction, they are passed to HookErrorHandler
+func AfterCommit(ctx context.Context, hook Hook) error {
+	t, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok {
+		return ErrNoTransaction
+	}
+	t.hooks.add(&t.hooks.commit, hook)
+	return nil
+}
+
+// AfterRollback registers the hook called after the transaction in the context is rolled back
+// (including failed commit). If savepoints are enabled, hooks registered within the savepoint are called
+// with the context of the outer transaction once the savepoint is rolled back,
+// whereas AfterCommit hooks registered within it are discarded
+func AfterRollback(ctx context.Context, hook Hook) error {
+	t, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok {
+		return ErrNoTransaction
+	}
+	t.hooks.add(&t.hooks.rollback, hook)
+	return nil
+}
+
+// hooks of the outermost transaction
+type hooks struct {
+	mu       sync.Mutex
+	commit   []Hook
+	rollback []Hook
+}
+
+func (h *hooks) add(list *[]Hook, hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*list = append(*list, hook)
+}
+
+// mark returns the number of registered hooks, so hooks registered later can be cut by cut
+func (h *hooks) mark() (commit, rollback int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.commit), len(h.rollback)
+}
+
+// cut removes hooks registered after mark and returns rollback hooks among them
+func (h *hooks) cut(commit, rollback int) []Hook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commit = h.commit[:commit]
+	cut := append([]Hook(nil), h.rollback[rollback:]...)
+	h.rollback = h.rollback[:rollback]
+	return cut
+}
+
+func (h *hooks) committed() []Hook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.commit
+}
+
+func (h *hooks) rolledBack() []Hook {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rollback
+}
+
+// runHooks calls every hook even if the previous one has failed
+func (g *DefaultWrapper) runHooks(ctx context.Context, stage string, hooks []Hook) {
+	for _, hook := range hooks {
+		if err := callHook(ctx, hook); err != nil {
+			g.handleHookError(ctx, fmt.Errorf("sqltx: after %s hook error: %w", stage, err))
+		}
+	}
+}
+
+func (g *DefaultWrapper) handleHookError(ctx context.Context, err error) {
+	if g.hookErrorHandler != nil {
+		g.hookErrorHandler(ctx, err)
+		return
+	}
+	g.logger.Warn("sqltx: hook error", "error", err.Error())
+}
+
+func callHook(ctx context.Context, hook Hook) (err error) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			err = fmt.Errorf("panic recovered:\n%v\n%s", perr, stackTrace())
+		}
+	}()
+	return hook(ctx)
+}
@@ -0,0 +1,146 @@
+package sqltx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/internal/session"
+)
+
+type hookCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (h *hookCalls) hook(name string, err error) Hook {
+	return func(ctx context.Context) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.calls = append(h.calls, name)
+		return err
+	}
+}
+
+func TestAfterCommit(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	var hookErrs []error
+	wrapper := NewDefaultWrapper(db, &noopLogger{}, WithHookErrorHandler(func(ctx context.Context, err error) {
+		hookErrs = append(hookErrs, err)
+	}))
+	calls := &hookCalls{}
+	errHook := errors.New("hook failure")
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, AfterCommit(ctx, calls.hook("first", errHook)))
+		require.NoError(t, AfterRollback(ctx, calls.hook("rollback", nil)))
+		return wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, AfterCommit(ctx, func(ctx context.Context) error {
+				panic("hook panic")
+			}))
+			require.NoError(t, AfterCommit(ctx, calls.hook("nested", nil)))
+			require.Empty(t, calls.calls, "hooks must not be called before commit")
+			return nil
+		})
+	})
+
+	require.NoError(t, err, "hook errors must not affect the result")
+	require.Equal(t, []string{"first", "nested"}, calls.calls)
+	require.Len(t, hookErrs, 2)
+	require.ErrorIs(t, hookErrs[0], errHook)
+	require.ErrorContains(t, hookErrs[1], "panic recovered")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAfterRollback(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+	calls := &hookCalls{}
+	errTx := errors.New("failure")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("connection lost"))
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, AfterCommit(ctx, calls.hook("commit", nil)))
+		require.NoError(t, AfterRollback(ctx, calls.hook("rollback", nil)))
+		return errTx
+	})
+	require.ErrorIs(t, err, errTx)
+
+	err = wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return AfterRollback(ctx, calls.hook("commit failed", nil))
+	})
+	require.ErrorContains(t, err, "commit error")
+
+	require.Equal(t, []string{"rollback", "commit failed"}, calls.calls)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAfterCommit_SavepointRolledBack(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{}, WithSavepoints(MySQL))
+	calls := &hookCalls{}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, AfterCommit(ctx, calls.hook("outer", nil)))
+		_ = wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, AfterCommit(ctx, calls.hook("discarded", nil)))
+			require.NoError(t, AfterRollback(ctx, calls.hook("savepoint rollback", nil)))
+			return errors.New("failure")
+		})
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"savepoint rollback", "outer"}, calls.calls)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAfterCommit_NoTransaction(t *testing.T) {
+	require.ErrorIs(t, AfterCommit(context.Background(), func(ctx context.Context) error { return nil }), ErrNoTransaction)
+	require.ErrorIs(t, AfterRollback(context.Background(), func(ctx context.Context) error { return nil }), ErrNoTransaction)
+}
+
+func TestSessionCleanup(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+	calls := &hookCalls{}
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT RELEASE_LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		require.True(t, session.Add(ctx, func(ctx context.Context, conn *sql.Conn) error {
+			calls.hook("cleanup", nil)(ctx)
+			_, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK('report')")
+			return err
+		}))
+		return AfterCommit(ctx, func(ctx context.Context) error {
+			require.Zero(t, db.Stats().InUse, "the connection must be returned to the pool before the hooks")
+			return calls.hook("commit", nil)(ctx)
+		})
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"cleanup", "commit"}, calls.calls)
+	require.False(t, session.Add(context.Background(), nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,55 @@
+// Package session keeps the cleanup functions of the database session of the transaction, e.g. release of MySQL
+// named locks. They are called on the connection of the transaction once it is finished, before the connection
+// is returned to the pool
+package session
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Cleanup cleans up the state of the session using the connection of the finished transaction
+type Cleanup func(ctx context.Context, conn *sql.Conn) error
+
+// Cleanups is the list of cleanup functions of the transaction
+type Cleanups struct {
+	mu   sync.Mutex
+	list []Cleanup
+}
+
+// Run calls every cleanup function in the order of registration and returns their errors
+func (c *Cleanups) Run(ctx context.Context, conn *sql.Conn) []error {
+	c.mu.Lock()
+	list := c.list
+	c.list = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for _, cleanup := range list {
+		if err := cleanup(ctx, conn); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+type key struct{}
+
+// WithCleanups returns the context of the transaction carrying its cleanup list
+func WithCleanups(ctx context.Context, c *Cleanups) context.Context {
+	return context.WithValue(ctx, key{}, c)
+}
+
+// Add registers the cleanup function of the session of the transaction in the context,
+// false is returned if there is no transaction
+func Add(ctx context.Context, cleanup Cleanup) bool {
+	c, ok := ctx.Value(key{}).(*Cleanups)
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.list = append(c.list, cleanup)
+	return true
+}
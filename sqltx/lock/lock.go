@@ -0,0 +1,231 @@
+// Package lock provides distributed locks based on database advisory locks
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/internal/session"
+)
+
+// ErrNotAcquired is returned when the lock is held by another session within the timeout
+var ErrNotAcquired = errors.New("lock: lock is not acquired within timeout")
+
+// Dialect specifies the advisory lock functions of the database
+type Dialect int
+
+const (
+	// MySQL uses GET_LOCK and RELEASE_LOCK
+	MySQL Dialect = iota
+	// Postgres uses pg_try_advisory_lock, pg_advisory_unlock and pg_try_advisory_xact_lock,
+	// the lock name is hashed into the 64-bit key
+	Postgres
+)
+
+// DefaultPollInterval is the interval of lock attempts in Postgres unless WithPollInterval is specified
+const DefaultPollInterval = 100 * time.Millisecond
+
+// Logger logs errors of scheduled tasks
+type Logger interface {
+	Warn(msg string, args ...any)
+}
+
+type option func(l *Locker)
+
+// WithPollInterval sets the interval of lock attempts in Postgres, which has no functions waiting for
+// an advisory lock with a timeout
+func WithPollInterval(d time.Duration) option {
+	return func(l *Locker) {
+		if d > 0 {
+			l.pollInterval = d
+		}
+	}
+}
+
+// WithLogger sets the logger of the tasks created by Task. Errors are not logged by default
+func WithLogger(logger Logger) option {
+	return func(l *Locker) {
+		if logger != nil {
+			l.logger = logger
+		}
+	}
+}
+
+// Locker acquires advisory locks
+type Locker struct {
+	db           *sql.DB
+	wrapper      sqltx.Wrapper
+	dialect      Dialect
+	pollInterval time.Duration
+	logger       Logger
+}
+
+// New creates Locker. Session-scoped locks are held on dedicated connections of the db,
+// transaction-scoped locks are acquired using the transaction of the wrapper
+func New(db *sql.DB, wrapper sqltx.Wrapper, dialect Dialect, opts ...option) *Locker {
+	l := &Locker{
+		db:           db,
+		wrapper:      wrapper,
+		dialect:      dialect,
+		pollInterval: DefaultPollInterval,
+		logger:       noopLogger{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// WithLock runs the function holding the session-scoped lock. The lock is held on a dedicated connection
+// and released once the function returns. Zero timeout means a single attempt.
+// ErrNotAcquired is returned if the lock is held by another session within the timeout
+func (l *Locker) WithLock(ctx context.Context, name string, timeout time.Duration, f func(ctx context.Context) error) (err error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("lock: cannot get connection: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil && !errors.Is(err, driver.ErrBadConn) {
+			l.logger.Warn("lock: cannot close connection", "lock", name, "error", err.Error())
+		}
+	}()
+
+	if err = l.acquire(ctx, conn, name, timeout, false); err != nil {
+		return err
+	}
+	defer func() {
+		if rErr := l.release(conn, name); rErr != nil {
+			// the lock is released when the session ends, so the connection is discarded
+			_ = conn.Raw(func(any) error {
+				return driver.ErrBadConn
+			})
+			l.logger.Warn("lock: cannot release lock, connection is discarded", "lock", name, "error", rErr.Error())
+		}
+	}()
+
+	return f(ctx)
+}
+
+// LockTx acquires the lock which is released when the transaction of the context is finished.
+// MySQL has no transaction-scoped named locks, so the lock is acquired on the connection of the transaction
+// and released on the same connection once the transaction is committed or rolled back.
+// sqltx.ErrNoTransaction is returned if there is no transaction in the context
+func (l *Locker) LockTx(ctx context.Context, name string, timeout time.Duration) error {
+	if sqltx.Depth(ctx) == 0 {
+		return sqltx.ErrNoTransaction
+	}
+	if err := l.acquire(ctx, l.wrapper.Connection(ctx), name, timeout, true); err != nil {
+		return err
+	}
+	if l.dialect != MySQL {
+		return nil
+	}
+
+	// the context of the transaction always carries the session of the transaction
+	session.Add(ctx, func(_ context.Context, conn *sql.Conn) error {
+		if err := l.release(conn, name); err != nil {
+			// the lock is released when the session ends, so the connection is discarded
+			_ = conn.Raw(func(any) error {
+				return driver.ErrBadConn
+			})
+			return fmt.Errorf("lock: cannot release lock %q, connection is discarded: %w", name, err)
+		}
+		return nil
+	})
+	return nil
+}
+
+// Task wraps the scheduled task, so it runs on only one instance at a time, e.g.:
+//
+//	scheduler.Add(time.Minute, locker.Task("cleanup", cleanup))
+//
+// The task is skipped if the lock is held by another instance
+func (l *Locker) Task(name string, task func(ctx context.Context)) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		err := l.WithLock(ctx, name, 0, func(ctx context.Context) error {
+			task(ctx)
+			return nil
+		})
+		if err != nil && !errors.Is(err, ErrNotAcquired) {
+			l.logger.Warn("lock: scheduled task is skipped", "lock", name, "error", err.Error())
+		}
+	}
+}
+
+// queryRower is implemented by sql.Conn and sqltx.Connection
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (l *Locker) acquire(ctx context.Context, conn queryRower, name string, timeout time.Duration, tx bool) error {
+	if l.dialect == MySQL {
+		var acquired sql.NullInt64
+		err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, timeout.Seconds()).Scan(&acquired)
+		if err != nil {
+			return fmt.Errorf("lock: cannot acquire lock %q: %w", name, err)
+		}
+		if acquired.Int64 != 1 {
+			return ErrNotAcquired
+		}
+		return nil
+	}
+
+	query := "SELECT pg_try_advisory_lock($1)"
+	if tx {
+		query = "SELECT pg_try_advisory_xact_lock($1)"
+	}
+	key := Key(name)
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := conn.QueryRowContext(ctx, query, key).Scan(&acquired); err != nil {
+			return fmt.Errorf("lock: cannot acquire lock %q: %w", name, err)
+		}
+		if acquired {
+			return nil
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return ErrNotAcquired
+		}
+		if wait > l.pollInterval {
+			wait = l.pollInterval
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *Locker) release(conn *sql.Conn, name string) error {
+	// the lock must be released even if the context is cancelled
+	ctx := context.Background()
+	var err error
+	if l.dialect == MySQL {
+		_, err = conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", name)
+	} else {
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", Key(name))
+	}
+	return err
+}
+
+// Key returns the Postgres advisory lock key of the name
+func Key(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Warn(string, ...any) {}
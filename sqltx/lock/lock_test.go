@@ -0,0 +1,198 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/lock"
+	"github.com/velmie/x/sqltx/sqltxtest"
+)
+
+type noopLogger struct{}
+
+func (noopLogger) Warn(string, ...any) {}
+
+func newLocker(t *testing.T, dialect lock.Dialect) (*lock.Locker, *sqltx.DefaultWrapper, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	wrapper := sqltx.NewDefaultWrapper(db, noopLogger{})
+	return lock.New(db, wrapper, dialect, lock.WithPollInterval(time.Millisecond)), wrapper, mock
+}
+
+func TestLocker_WithLockMySQL(t *testing.T) {
+	locker, _, mock := newLocker(t, lock.MySQL)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?)")).
+		WithArgs("cleanup", float64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT RELEASE_LOCK(?)")).
+		WithArgs("cleanup").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?)")).
+		WithArgs("cleanup", float64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+
+	called := false
+	err := locker.WithLock(context.Background(), "cleanup", 2*time.Second, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, called)
+
+	err = locker.WithLock(context.Background(), "cleanup", 0, func(ctx context.Context) error {
+		t.Fatal("function must not be called without lock")
+		return nil
+	})
+	require.ErrorIs(t, err, lock.ErrNotAcquired)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocker_WithLockPostgres(t *testing.T) {
+	locker, _, mock := newLocker(t, lock.Postgres)
+	key := lock.Key("cleanup")
+	errJob := errors.New("job failure")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(false))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WithArgs(key).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(true))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(key).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := locker.WithLock(context.Background(), "cleanup", time.Second, func(ctx context.Context) error {
+		return errJob
+	})
+
+	require.ErrorIs(t, err, errJob)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocker_LockTx(t *testing.T) {
+	locker, wrapper, mock := newLocker(t, lock.Postgres)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_xact_lock($1)")).
+		WithArgs(lock.Key("report")).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(true))
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return locker.LockTx(ctx, "report", time.Second)
+	})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, locker.LockTx(context.Background(), "report", 0), sqltx.ErrNoTransaction)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// namedLocks simulates MySQL named locks which are held by the database session
+type namedLocks struct {
+	mu      sync.Mutex
+	holders map[string]int
+	queries []sqltxtest.Query
+}
+
+func (n *namedLocks) handle(q sqltxtest.Query) (*sqltxtest.Rows, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.queries = append(n.queries, q)
+	name := q.Args[0].(string)
+	result := int64(0)
+	switch q.SQL {
+	case "SELECT GET_LOCK(?, ?)":
+		if holder, ok := n.holders[name]; !ok || holder == q.Session {
+			n.holders[name] = q.Session
+			result = 1
+		}
+	case "SELECT RELEASE_LOCK(?)":
+		if n.holders[name] == q.Session {
+			delete(n.holders, name)
+			result = 1
+		}
+	default:
+		return nil, sqltxtest.ErrQueryNotSupported
+	}
+	return &sqltxtest.Rows{Columns: []string{"result"}, Values: [][]any{{result}}}, nil
+}
+
+func (n *namedLocks) held(name string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, ok := n.holders[name]
+	return ok
+}
+
+func TestLocker_LockTxMySQL(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper()
+	defer wrapper.Close()
+	locks := &namedLocks{holders: map[string]int{}}
+	wrapper.Handle(locks.handle)
+	locker := lock.New(wrapper.DB(), wrapper, lock.MySQL)
+	errJob := errors.New("job failure")
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "Commit"},
+		{name: "Rollback", err: errJob},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locks.queries = nil
+			err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+				if err := locker.LockTx(ctx, "report", time.Second); err != nil {
+					return err
+				}
+				require.True(t, locks.held("report"))
+				return tt.err
+			})
+
+			require.ErrorIs(t, err, tt.err)
+			require.False(t, locks.held("report"), "the lock must be released once the transaction is finished")
+			require.Len(t, locks.queries, 2)
+			acquire, release := locks.queries[0], locks.queries[1]
+			require.True(t, acquire.InTx)
+			require.False(t, release.InTx)
+			require.Equal(t, "SELECT RELEASE_LOCK(?)", release.SQL)
+			require.Equal(t, acquire.Session, release.Session, "the lock must be released on the session of the transaction")
+		})
+	}
+
+	require.ErrorIs(t, locker.LockTx(context.Background(), "report", 0), sqltx.ErrNoTransaction)
+}
+
+func TestLocker_Task(t *testing.T) {
+	locker, _, mock := newLocker(t, lock.MySQL)
+
+	mock.ExpectQuery("GET_LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec("RELEASE_LOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("GET_LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+
+	runs := 0
+	task := locker.Task("cleanup", func(ctx context.Context) {
+		runs++
+	})
+	task(context.Background())
+	// the lock is held by another instance
+	task(context.Background())
+
+	require.Equal(t, 1, runs)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
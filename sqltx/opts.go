@@ -3,20 +3,59 @@ package sqltx
 import "database/sql"
 
 // Option is a function type that modifies the transaction options.
-type Option func(opts *sql.TxOptions)
+// Since v1.1.0 it is opaque (it used to be func(*sql.TxOptions)), use ApplyOptions in order to read the options
+type Option func(opts *options)
+
+type options struct {
+	tx    *sql.TxOptions
+	retry *RetryPolicy
+}
+
+// Options are the transaction options set by Option functions
+type Options struct {
+	// TxOptions is nil if neither ReadOnly nor WithIsolationLevel is used
+	TxOptions *sql.TxOptions
+	// Retry is nil if WithRetry is not used
+	Retry *RetryPolicy
+}
+
+// ApplyOptions returns the options set by the given Option functions, e.g. in order to implement Wrapper
+func ApplyOptions(opts ...Option) Options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return Options{TxOptions: o.tx, Retry: o.retry}
+}
+
+func (o *options) txOptions() *sql.TxOptions {
+	if o.tx == nil {
+		o.tx = &sql.TxOptions{}
+	}
+	return o.tx
+}
 
 // ReadOnly returns an Option to set the transaction as read-only.
 func ReadOnly() Option {
-	return func(opts *sql.TxOptions) {
+	return func(opts *options) {
 		// Setting the transaction to be read-only.
-		opts.ReadOnly = true
+		opts.txOptions().ReadOnly = true
 	}
 }
 
 // WithIsolationLevel returns an Option to set the isolation level for the transaction.
 // The 'level' parameter specifies the desired isolation level.
 func WithIsolationLevel(level sql.IsolationLevel) Option {
-	return func(opts *sql.TxOptions) {
-		opts.Isolation = level
+	return func(opts *options) {
+		opts.txOptions().Isolation = level
+	}
+}
+
+// WithRetry returns an Option to re-run the whole transaction if it fails with an error which is considered
+// retryable by the policy classifier, e.g. a deadlock.
+// Retry is not possible within the outer transaction, so the nested call fails with ErrNestedRetry
+func WithRetry(policy RetryPolicy) Option {
+	return func(opts *options) {
+		opts.retry = &policy
 	}
 }
@@ -0,0 +1,69 @@
+package otelsqltx
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logger logs slow queries
+type Logger interface {
+	Warn(msg string, args ...any)
+}
+
+type option func(o *options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	system         string
+	sanitize       func(statement string) string
+	slowThreshold  time.Duration
+	logger         Logger
+}
+
+// WithTracerProvider sets the provider of the tracer, e.g. the one created by otelx.CreateTracerProvider.
+// The global provider is used by default
+func WithTracerProvider(tp trace.TracerProvider) option {
+	return func(o *options) {
+		if tp != nil {
+			o.tracerProvider = tp
+		}
+	}
+}
+
+// WithMeterProvider sets the provider of the meter. The global provider is used by default
+func WithMeterProvider(mp metric.MeterProvider) option {
+	return func(o *options) {
+		if mp != nil {
+			o.meterProvider = mp
+		}
+	}
+}
+
+// WithDBSystem sets the db.system attribute, e.g. "mysql" or "postgresql"
+func WithDBSystem(system string) option {
+	return func(o *options) {
+		o.system = system
+	}
+}
+
+// WithStatementSanitizer sets the function applied to statements before they are recorded in spans and logs,
+// e.g. SanitizeLiterals. Statements are recorded as is by default
+func WithStatementSanitizer(sanitize func(statement string) string) option {
+	return func(o *options) {
+		o.sanitize = sanitize
+	}
+}
+
+// WithSlowQueryLog logs queries which take longer than the threshold. Statements are sanitized if the sanitizer is set,
+// arguments are logged as their types only
+func WithSlowQueryLog(threshold time.Duration, logger Logger) option {
+	return func(o *options) {
+		if threshold > 0 && logger != nil {
+			o.slowThreshold = threshold
+			o.logger = logger
+		}
+	}
+}
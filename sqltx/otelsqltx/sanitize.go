@@ -0,0 +1,27 @@
+package otelsqltx
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	stringLiterals  = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	numericLiterals = regexp.MustCompile(`(^|[^\w$.])\d+(?:\.\d+)?\b`)
+)
+
+// SanitizeLiterals replaces string and numeric literals of the statement with '?'.
+// Placeholders ($1) and identifiers are kept
+func SanitizeLiterals(statement string) string {
+	statement = stringLiterals.ReplaceAllString(statement, "?")
+	return numericLiterals.ReplaceAllString(statement, "${1}?")
+}
+
+// operation returns the first keyword of the statement, e.g. SELECT
+func operation(statement string) string {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
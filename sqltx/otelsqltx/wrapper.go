@@ -0,0 +1,259 @@
+// Package otelsqltx instruments sqltx.Wrapper with OpenTelemetry tracing and metrics
+package otelsqltx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/velmie/x/sqltx"
+)
+
+// instrumentationName is the name of the tracer and the meter
+const instrumentationName = "github.com/velmie/x/sqltx/otelsqltx"
+
+// Metric names
+const (
+	OperationDurationMetric   = "db.client.operation.duration"
+	TransactionDurationMetric = "db.client.transaction.duration"
+)
+
+// Transaction outcome recorded as the db.transaction.outcome attribute
+const (
+	OutcomeCommit   = "commit"
+	OutcomeRollback = "rollback"
+)
+
+// Attribute keys which are not defined by semantic conventions
+const (
+	TransactionOutcomeKey = attribute.Key("db.transaction.outcome")
+	TransactionDepthKey   = attribute.Key("db.transaction.depth")
+	ErrorTypeKey          = attribute.Key("error.type")
+)
+
+// Wrapper decorates sqltx.Wrapper: it creates spans for queries and transactions,
+// records their durations and logs slow queries
+type Wrapper struct {
+	wrapper    sqltx.Wrapper
+	tracer     trace.Tracer
+	opDuration metric.Float64Histogram
+	txDuration metric.Float64Histogram
+	attrs      []attribute.KeyValue
+	sanitize   func(statement string) string
+	slow       time.Duration
+	logger     Logger
+}
+
+// New creates Wrapper decorating the given one
+func New(w sqltx.Wrapper, opts ...option) (*Wrapper, error) {
+	o := &options{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	meter := o.meterProvider.Meter(instrumentationName)
+	opDuration, err := meter.Float64Histogram(
+		OperationDurationMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of database queries"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s histogram: %w", OperationDurationMetric, err)
+	}
+	txDuration, err := meter.Float64Histogram(
+		TransactionDurationMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of database transactions"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create %s histogram: %w", TransactionDurationMetric, err)
+	}
+
+	iw := &Wrapper{
+		wrapper:    w,
+		tracer:     o.tracerProvider.Tracer(instrumentationName),
+		opDuration: opDuration,
+		txDuration: txDuration,
+		sanitize:   o.sanitize,
+		slow:       o.slowThreshold,
+		logger:     o.logger,
+	}
+	if o.system != "" {
+		iw.attrs = append(iw.attrs, semconv.DBSystemKey.String(o.system))
+	}
+	return iw, nil
+}
+
+// WithTransaction runs the transaction of the decorated wrapper within the span. The span gets begin event
+// and commit or rollback event once the transaction is finished
+func (w *Wrapper) WithTransaction(ctx context.Context, f func(ctx context.Context) error, opts ...sqltx.Option) error {
+	depth := sqltx.Depth(ctx) + 1
+	ctx, span := w.tracer.Start(ctx, "transaction",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(w.attrs...),
+		trace.WithAttributes(TransactionDepthKey.Int(depth)),
+	)
+	defer span.End()
+
+	start := time.Now()
+	outcome := ""
+	err := w.wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+		span.AddEvent("begin")
+		if depth == 1 {
+			// the outcome of the outermost transaction is known from hooks only, since commit may fail
+			_ = sqltx.AfterCommit(ctx, func(context.Context) error {
+				outcome = OutcomeCommit
+				return nil
+			})
+			_ = sqltx.AfterRollback(ctx, func(context.Context) error {
+				outcome = OutcomeRollback
+				return nil
+			})
+		}
+		return f(ctx)
+	}, opts...)
+
+	if outcome == "" {
+		outcome = OutcomeCommit
+		if err != nil {
+			outcome = OutcomeRollback
+		}
+	}
+	span.AddEvent(outcome)
+	span.SetAttributes(TransactionOutcomeKey.String(outcome))
+	w.recordError(span, err)
+
+	attrs := append([]attribute.KeyValue{TransactionOutcomeKey.String(outcome)}, w.attrs...)
+	w.txDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+	return err
+}
+
+// Connection returns instrumented connection of the decorated wrapper.
+// Methods without context use the given context as the parent of spans
+func (w *Wrapper) Connection(ctx context.Context) sqltx.Connection {
+	return &connection{conn: w.wrapper.Connection(ctx), ctx: ctx, w: w}
+}
+
+// observation is started before the query and finished after it
+type observation struct {
+	w         *Wrapper
+	ctx       context.Context
+	span      trace.Span
+	start     time.Time
+	statement string
+	args      []any
+	attrs     []attribute.KeyValue
+}
+
+func (w *Wrapper) observe(ctx context.Context, statement string, args []any) (context.Context, *observation) {
+	if w.sanitize != nil {
+		statement = w.sanitize(statement)
+	}
+	op := operation(statement)
+	attrs := append([]attribute.KeyValue{semconv.DBOperationKey.String(op)}, w.attrs...)
+
+	name := op
+	if name == "" {
+		name = "query"
+	}
+	ctx, span := w.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(semconv.DBStatementKey.String(statement)),
+	)
+	return ctx, &observation{w: w, ctx: ctx, span: span, start: time.Now(), statement: statement, args: args, attrs: attrs}
+}
+
+func (o *observation) end(err error) {
+	d := time.Since(o.start)
+	o.w.recordError(o.span, err)
+	o.span.End()
+
+	attrs := o.attrs
+	if err != nil {
+		attrs = append(attrs, ErrorTypeKey.String(fmt.Sprintf("%T", err)))
+	}
+	o.w.opDuration.Record(o.ctx, d.Seconds(), metric.WithAttributes(attrs...))
+
+	if o.w.slow > 0 && d >= o.w.slow {
+		o.w.logger.Warn("sqltx: slow query",
+			"statement", o.statement,
+			"args", redact(o.args),
+			"duration", d.String(),
+		)
+	}
+}
+
+func (w *Wrapper) recordError(span trace.Span, err error) {
+	if err == nil || err == sql.ErrNoRows {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// redact replaces arguments with their types
+func redact(args []any) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = fmt.Sprintf("%T", arg)
+	}
+	return redacted
+}
+
+type connection struct {
+	conn sqltx.Connection
+	ctx  context.Context
+	w    *Wrapper
+}
+
+func (c *connection) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, o := c.w.observe(ctx, query, args)
+	res, err := c.conn.ExecContext(ctx, query, args...)
+	o.end(err)
+	return res, err
+}
+
+func (c *connection) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, o := c.w.observe(ctx, query, nil)
+	stmt, err := c.conn.PrepareContext(ctx, query)
+	o.end(err)
+	return stmt, err
+}
+
+func (c *connection) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, o := c.w.observe(ctx, query, args)
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	o.end(err)
+	return rows, err
+}
+
+func (c *connection) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, o := c.w.observe(ctx, query, args)
+	row := c.conn.QueryRowContext(ctx, query, args...)
+	o.end(row.Err())
+	return row
+}
+
+func (c *connection) QueryRow(query string, args ...any) *sql.Row {
+	return c.QueryRowContext(c.ctx, query, args...)
+}
+
+func (c *connection) Query(query string, args ...any) (*sql.Rows, error) {
+	return c.QueryContext(c.ctx, query, args...)
+}
+
+func (c *connection) Exec(query string, args ...any) (sql.Result, error) {
+	return c.ExecContext(c.ctx, query, args...)
+}
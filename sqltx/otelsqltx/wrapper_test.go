@@ -0,0 +1,171 @@
+package otelsqltx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/otelsqltx"
+)
+
+type telemetry struct {
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+func (tm *telemetry) span(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range tm.spans.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("span %q is not found", name)
+	return nil
+}
+
+func (tm *telemetry) histogram(t *testing.T, name string) metricdata.Histogram[float64] {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, tm.reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Histogram[float64])
+			}
+		}
+	}
+	t.Fatalf("metric %q is not found", name)
+	return metricdata.Histogram[float64]{}
+}
+
+func attr(attrs []attribute.KeyValue, key attribute.Key) string {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.Emit()
+		}
+	}
+	return ""
+}
+
+type logRecorder struct {
+	mu   sync.Mutex
+	logs []string
+}
+
+func (l *logRecorder) Warn(msg string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logs = append(l.logs, fmt.Sprint(append([]any{msg}, args...)...))
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Warn(string, ...any) {}
+
+func newWrapper(t *testing.T) (*otelsqltx.Wrapper, sqlmock.Sqlmock, *telemetry, *logRecorder) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	tm := &telemetry{spans: tracetest.NewSpanRecorder(), reader: sdkmetric.NewManualReader()}
+	logs := &logRecorder{}
+	w, err := otelsqltx.New(sqltx.NewDefaultWrapper(db, noopLogger{}),
+		otelsqltx.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tm.spans))),
+		otelsqltx.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(tm.reader))),
+		otelsqltx.WithDBSystem("mysql"),
+		otelsqltx.WithStatementSanitizer(otelsqltx.SanitizeLiterals),
+		otelsqltx.WithSlowQueryLog(time.Nanosecond, logs),
+	)
+	require.NoError(t, err)
+	return w, mock, tm, logs
+}
+
+func TestWrapper_Query(t *testing.T) {
+	w, mock, tm, logs := newWrapper(t)
+
+	mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("john"))
+	mock.ExpectExec("UPDATE users").WillReturnError(errors.New("read only"))
+
+	ctx := context.Background()
+	var name string
+	require.NoError(t, w.Connection(ctx).QueryRowContext(ctx, "SELECT name FROM users WHERE email = 'john@example.com' AND id = ?", 42).Scan(&name))
+	_, err := w.Connection(ctx).Exec("UPDATE users SET age = 30 WHERE id = $1", 1)
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	sel := tm.span(t, "SELECT")
+	require.Equal(t, "SELECT name FROM users WHERE email = ? AND id = ?", attr(sel.Attributes(), "db.statement"))
+	require.Equal(t, "mysql", attr(sel.Attributes(), "db.system"))
+	require.Equal(t, codes.Unset, sel.Status().Code)
+
+	upd := tm.span(t, "UPDATE")
+	require.Equal(t, "UPDATE users SET age = ? WHERE id = $1", attr(upd.Attributes(), "db.statement"))
+	require.Equal(t, codes.Error, upd.Status().Code)
+
+	hist := tm.histogram(t, otelsqltx.OperationDurationMetric)
+	require.Len(t, hist.DataPoints, 2)
+
+	require.Len(t, logs.logs, 2)
+	require.Contains(t, logs.logs[0], "[int]")
+	require.NotContains(t, logs.logs[0], "john@example.com")
+}
+
+func TestWrapper_Transaction(t *testing.T) {
+	w, mock, tm, _ := newWrapper(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("connection lost"))
+
+	err := w.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return w.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := w.Connection(ctx).ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "john")
+			return err
+		})
+	})
+	require.NoError(t, err)
+
+	err = w.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return nil
+	})
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	spans := tm.spans.Ended()
+	require.Len(t, spans, 4)
+	insert, nested, committed, failed := spans[0], spans[1], spans[2], spans[3]
+	require.Equal(t, nested.SpanContext().SpanID(), insert.Parent().SpanID())
+	require.Equal(t, committed.SpanContext().SpanID(), nested.Parent().SpanID())
+	require.Equal(t, "2", attr(nested.Attributes(), otelsqltx.TransactionDepthKey))
+	require.Equal(t, otelsqltx.OutcomeCommit, attr(committed.Attributes(), otelsqltx.TransactionOutcomeKey))
+	require.Equal(t, "begin", committed.Events()[0].Name)
+	require.Equal(t, otelsqltx.OutcomeCommit, committed.Events()[1].Name)
+	require.Equal(t, otelsqltx.OutcomeRollback, attr(failed.Attributes(), otelsqltx.TransactionOutcomeKey))
+	require.Equal(t, codes.Error, failed.Status().Code)
+
+	hist := tm.histogram(t, otelsqltx.TransactionDurationMetric)
+	var count uint64
+	for _, dp := range hist.DataPoints {
+		count += dp.Count
+	}
+	require.Equal(t, uint64(3), count)
+}
@@ -0,0 +1,176 @@
+// Package outbox implements the transactional outbox pattern on top of sqltx: messages are saved within
+// the business transaction and published by Relay once the transaction is committed
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/velmie/x/sqltx"
+)
+
+// DefaultTable is the name of the outbox table unless WithTable is specified
+const DefaultTable = "outbox"
+
+// Status of the message stored in the outbox table
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Dialect specifies SQL syntax differences of the databases
+type Dialect int
+
+const (
+	MySQL Dialect = iota
+	Postgres
+)
+
+// placeholder returns the placeholder of the n-th (starting from 1) query argument
+func (d Dialect) placeholder(n int) string {
+	if d == Postgres {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// placeholders returns comma separated placeholders of the arguments from..to
+func (d Dialect) placeholders(from, to int) string {
+	ph := make([]string, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		ph = append(ph, d.placeholder(i))
+	}
+	return strings.Join(ph, ", ")
+}
+
+// Message is an event or a command which is published after the transaction is committed
+type Message struct {
+	// ID is assigned by the database
+	ID int64
+	// Topic is the destination of the message, e.g. a topic or a queue name
+	Topic string
+	// Key is used by the publisher for partitioning, it can be empty
+	Key     string
+	Payload []byte
+	Headers map[string]string
+	// Attempts is the number of failed publish attempts
+	Attempts  int
+	CreatedAt time.Time
+}
+
+type option func(o *Outbox)
+
+// WithTable sets the name of the outbox table, DefaultTable is used by default
+func WithTable(name string) option {
+	return func(o *Outbox) {
+		if name != "" {
+			o.table = name
+		}
+	}
+}
+
+// WithDialect sets the database dialect, MySQL is used by default
+func WithDialect(d Dialect) option {
+	return func(o *Outbox) {
+		o.dialect = d
+	}
+}
+
+// WithClock replaces time.Now, e.g. in tests
+func WithClock(now func() time.Time) option {
+	return func(o *Outbox) {
+		if now != nil {
+			o.now = now
+		}
+	}
+}
+
+// WithNotify sets the function called once the transaction which has enqueued messages is committed,
+// e.g. Relay.Wake, so the messages are published without waiting for the next poll
+func WithNotify(notify func()) option {
+	return func(o *Outbox) {
+		o.notify = notify
+	}
+}
+
+// Outbox saves messages into the outbox table
+type Outbox struct {
+	wrapper sqltx.Wrapper
+	table   string
+	dialect Dialect
+	now     func() time.Time
+	notify  func()
+}
+
+// New creates Outbox using the wrapper in order to take part in the transaction of the context
+func New(wrapper sqltx.Wrapper, opts ...option) *Outbox {
+	o := &Outbox{
+		wrapper: wrapper,
+		table:   DefaultTable,
+		dialect: MySQL,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Enqueue saves messages within the transaction of the context, so they are published only if the transaction
+// is committed. sqltx.ErrNoTransaction is returned if there is no transaction in the context
+func (o *Outbox) Enqueue(ctx context.Context, msgs ...Message) error {
+	if sqltx.Depth(ctx) == 0 {
+		return sqltx.ErrNoTransaction
+	}
+	query := fmt.Sprintf(
+		"INSERT INTO %s (topic, msg_key, payload, headers, status, attempts, next_attempt_at, created_at) VALUES (%s)",
+		o.table,
+		o.dialect.placeholders(1, 8),
+	)
+	conn := o.wrapper.Connection(ctx)
+	now := o.now().UTC()
+	for _, msg := range msgs {
+		headers, err := encodeHeaders(msg.Headers)
+		if err != nil {
+			return err
+		}
+		_, err = conn.ExecContext(ctx, query, msg.Topic, msg.Key, msg.Payload, headers, StatusPending, 0, now, now)
+		if err != nil {
+			return fmt.Errorf("outbox: cannot enqueue message: %w", err)
+		}
+	}
+	if o.notify == nil || len(msgs) == 0 {
+		return nil
+	}
+	return sqltx.AfterCommit(ctx, func(context.Context) error {
+		o.notify()
+		return nil
+	})
+}
+
+func encodeHeaders(headers map[string]string) (any, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: cannot encode headers: %w", err)
+	}
+	return string(b), nil
+}
+
+func decodeHeaders(headers *string) (map[string]string, error) {
+	if headers == nil || *headers == "" {
+		return nil, nil
+	}
+	var h map[string]string
+	if err := json.Unmarshal([]byte(*headers), &h); err != nil {
+		return nil, fmt.Errorf("outbox: cannot decode headers: %w", err)
+	}
+	return h, nil
+}
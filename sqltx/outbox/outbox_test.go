@@ -0,0 +1,303 @@
+package outbox_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/outbox"
+)
+
+var now = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func clock() time.Time {
+	return now
+}
+
+func newOutbox(t *testing.T) (*outbox.Outbox, *sqltx.DefaultWrapper, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	wrapper := sqltx.NewDefaultWrapper(db, noopLogger{})
+	return outbox.New(wrapper, outbox.WithClock(clock), outbox.WithTable("events")), wrapper, mock
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Warn(string, ...any) {}
+
+func TestOutbox_Enqueue(t *testing.T) {
+	o, wrapper, mock := newOutbox(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO events (topic, msg_key, payload, headers, status, attempts, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")).
+		WithArgs("orders", "42", []byte("{}"), `{"type":"created"}`, outbox.StatusPending, 0, now, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO events").
+		WithArgs("orders", "", []byte("{}"), nil, outbox.StatusPending, 0, now, now).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return o.Enqueue(ctx,
+			outbox.Message{Topic: "orders", Key: "42", Payload: []byte("{}"), Headers: map[string]string{"type": "created"}},
+			outbox.Message{Topic: "orders", Payload: []byte("{}")},
+		)
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutbox_EnqueueWithoutTransaction(t *testing.T) {
+	o, _, mock := newOutbox(t)
+
+	err := o.Enqueue(context.Background(), outbox.Message{Topic: "orders"})
+
+	require.ErrorIs(t, err, sqltx.ErrNoTransaction)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutbox_EnqueueNotify(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	wrapper := sqltx.NewDefaultWrapper(db, noopLogger{})
+	var notified int
+	o := outbox.New(wrapper, outbox.WithNotify(func() {
+		notified++
+	}))
+	errRollback := errors.New("rollback")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectRollback()
+
+	err = wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if err := o.Enqueue(ctx, outbox.Message{Topic: "orders"}); err != nil {
+			return err
+		}
+		require.Zero(t, notified, "notify must not be called before commit")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, notified)
+
+	err = wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		if err := o.Enqueue(ctx, outbox.Message{Topic: "orders"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+	require.Equal(t, 1, notified)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutbox_PostgresPlaceholders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	wrapper := sqltx.NewDefaultWrapper(db, noopLogger{})
+	o := outbox.New(wrapper, outbox.WithDialect(outbox.Postgres))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO outbox (topic, msg_key, payload, headers, status, attempts, next_attempt_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return o.Enqueue(ctx, outbox.Message{Topic: "orders"})
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func messageRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "topic", "msg_key", "payload", "headers", "attempts", "created_at"})
+}
+
+func TestRelay_Process(t *testing.T) {
+	o, _, mock := newOutbox(t)
+	errBroker := errors.New("broker is unavailable")
+	var (
+		published []int64
+		dead      []int64
+	)
+	publisher := outbox.PublisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		if msg.Topic == "broken" {
+			return errBroker
+		}
+		published = append(published, msg.ID)
+		return nil
+	})
+	relay := outbox.NewRelay(o, publisher,
+		outbox.WithBatchSize(10),
+		outbox.WithMaxAttempts(3),
+		outbox.WithRetryBackoff(time.Second, time.Minute),
+		outbox.WithDeadLetterHandler(func(ctx context.Context, msg outbox.Message, err error) {
+			require.ErrorIs(t, err, errBroker)
+			dead = append(dead, msg.ID)
+		}),
+	)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, topic, msg_key, payload, headers, attempts, created_at FROM events WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT 10 FOR UPDATE SKIP LOCKED")).
+		WithArgs(outbox.StatusPending, now).
+		WillReturnRows(messageRows().
+			AddRow(1, "orders", "1", []byte("a"), `{"type":"created"}`, 0, now).
+			AddRow(2, "broken", "", []byte("b"), nil, 1, now).
+			AddRow(3, "broken", "", []byte("c"), nil, 2, now))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE events SET status = ?, delivered_at = ?, last_error = NULL WHERE id = ?")).
+		WithArgs(outbox.StatusDelivered, now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE events SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?")).
+		WithArgs(outbox.StatusPending, 2, now.Add(2*time.Second), errBroker.Error(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE events SET status").
+		WithArgs(outbox.StatusDead, 3, now.Add(4*time.Second), errBroker.Error(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := relay.Process(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, []int64{1}, published)
+	require.Equal(t, []int64{3}, dead)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_ProcessDatabaseError(t *testing.T) {
+	o, _, mock := newOutbox(t)
+	relay := outbox.NewRelay(o, outbox.PublisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		return nil
+	}))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WillReturnRows(messageRows().AddRow(1, "orders", "", nil, nil, 0, now))
+	mock.ExpectExec("UPDATE events").WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	_, err := relay.Process(context.Background())
+
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_StartStop(t *testing.T) {
+	o, _, mock := newOutbox(t)
+	relay := outbox.NewRelay(o, outbox.PublisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		return nil
+	}), outbox.WithPollInterval(10*time.Millisecond), outbox.WithBatchSize(1))
+
+	// the first batch is full, so the next one is fetched immediately
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WillReturnRows(messageRows().AddRow(1, "orders", "", nil, nil, 0, now))
+	mock.ExpectExec("UPDATE events").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WillReturnRows(messageRows())
+	mock.ExpectCommit()
+
+	started := make(chan error)
+	go func() {
+		started <- relay.Start()
+	}()
+	waitExpectations(t, mock)
+
+	require.NoError(t, relay.Stop(context.Background()))
+	select {
+	case err := <-started:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Start has not returned after Stop")
+	}
+}
+
+func TestRelay_Restart(t *testing.T) {
+	o, _, mock := newOutbox(t)
+	relay := outbox.NewRelay(o, outbox.PublisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		return nil
+	}), outbox.WithPollInterval(time.Hour))
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT").WillReturnRows(messageRows())
+		mock.ExpectCommit()
+
+		started := make(chan error)
+		go func() {
+			started <- relay.Start()
+		}()
+		// the restarted relay keeps polling
+		relay.Wake()
+		waitExpectations(t, mock)
+		select {
+		case <-started:
+			t.Fatalf("Start #%d has returned before Stop", i+1)
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		require.NoError(t, relay.Stop(context.Background()))
+		select {
+		case err := <-started:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatalf("Start #%d has not returned after Stop", i+1)
+		}
+	}
+	require.NoError(t, relay.Stop(context.Background()), "repeated Stop must not fail")
+}
+
+func TestRelay_Wake(t *testing.T) {
+	o, _, mock := newOutbox(t)
+	published := make(chan int64, 1)
+	relay := outbox.NewRelay(o, outbox.PublisherFunc(func(ctx context.Context, msg outbox.Message) error {
+		published <- msg.ID
+		return nil
+	}), outbox.WithPollInterval(time.Hour))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WillReturnRows(messageRows().AddRow(7, "orders", "", nil, nil, 0, now))
+	mock.ExpectExec("UPDATE events").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	started := make(chan error)
+	go func() {
+		started <- relay.Start()
+	}()
+	relay.Wake()
+
+	select {
+	case id := <-published:
+		require.Equal(t, int64(7), id)
+	case <-time.After(time.Second):
+		t.Fatal("the relay has not polled on wake-up")
+	}
+	waitExpectations(t, mock)
+	require.NoError(t, relay.Stop(context.Background()))
+	require.NoError(t, <-started)
+}
+
+// waitExpectations waits until the relay running in background meets the expectations
+func waitExpectations(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, time.Millisecond)
+}
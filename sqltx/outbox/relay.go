@@ -0,0 +1,334 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/velmie/x/sqltx"
+)
+
+// Publisher delivers messages to the broker
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// PublisherFunc allows to use an ordinary function as Publisher
+type PublisherFunc func(ctx context.Context, msg Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+// DeadLetterHandler is called when the message is moved to dead letters after the last failed attempt
+type DeadLetterHandler func(ctx context.Context, msg Message, err error)
+
+// Logger specifies the logger of the relay
+type Logger interface {
+	Warn(msg string, args ...any)
+}
+
+// Default relay settings
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+	DefaultMaxAttempts  = 10
+	DefaultRetryInitial = time.Second
+	DefaultRetryMax     = 10 * time.Minute
+)
+
+type relayOption func(r *Relay)
+
+// WithBatchSize sets the maximum number of messages published within one transaction
+func WithBatchSize(n int) relayOption {
+	return func(r *Relay) {
+		if n > 0 {
+			r.batchSize = n
+		}
+	}
+}
+
+// WithPollInterval sets the interval of polling the outbox table
+func WithPollInterval(d time.Duration) relayOption {
+	return func(r *Relay) {
+		if d > 0 {
+			r.interval = d
+		}
+	}
+}
+
+// WithMaxAttempts sets the number of publish attempts after which the message is moved to dead letters
+func WithMaxAttempts(n int) relayOption {
+	return func(r *Relay) {
+		if n > 0 {
+			r.maxAttempts = n
+		}
+	}
+}
+
+// WithRetryBackoff sets the delay before the next attempt, it is doubled after each failed attempt up to max
+func WithRetryBackoff(initial, max time.Duration) relayOption {
+	return func(r *Relay) {
+		if initial > 0 {
+			r.retryInitial = initial
+		}
+		if max > 0 {
+			r.retryMax = max
+		}
+	}
+}
+
+// WithDeadLetterHandler sets the handler called when the message is moved to dead letters
+func WithDeadLetterHandler(h DeadLetterHandler) relayOption {
+	return func(r *Relay) {
+		r.deadLetter = h
+	}
+}
+
+// WithLogger sets the logger, errors are not logged by default
+func WithLogger(logger Logger) relayOption {
+	return func(r *Relay) {
+		if logger != nil {
+			r.logger = logger
+		}
+	}
+}
+
+// Relay publishes pending messages of the outbox. It implements bootstrap.Service
+type Relay struct {
+	outbox       *Outbox
+	publisher    Publisher
+	logger       Logger
+	deadLetter   DeadLetterHandler
+	batchSize    int
+	interval     time.Duration
+	maxAttempts  int
+	retryInitial time.Duration
+	retryMax     time.Duration
+	// wake is buffered, so a signal sent while the batch is processed is not lost
+	wake chan struct{}
+
+	mu   sync.Mutex
+	stop chan struct{}
+	// done is closed once Start returns
+	done chan struct{}
+	// cancel cancels the context of the running batch
+	cancel context.CancelFunc
+}
+
+// NewRelay creates Relay polling the outbox table
+func NewRelay(outbox *Outbox, publisher Publisher, opts ...relayOption) *Relay {
+	r := &Relay{
+		outbox:       outbox,
+		publisher:    publisher,
+		logger:       noopLogger{},
+		batchSize:    DefaultBatchSize,
+		interval:     DefaultPollInterval,
+		maxAttempts:  DefaultMaxAttempts,
+		retryInitial: DefaultRetryInitial,
+		retryMax:     DefaultRetryMax,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Start polls the outbox table and blocks until the relay is stopped. The relay can be started again after Stop
+func (r *Relay) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop, done := make(chan struct{}), make(chan struct{})
+	defer close(done)
+	r.mu.Lock()
+	r.stop, r.done, r.cancel = stop, done, cancel
+	r.mu.Unlock()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			r.poll(ctx)
+		case <-r.wake:
+			r.poll(ctx)
+		}
+	}
+}
+
+// Stop stops polling and waits for the running batch to finish
+func (r *Relay) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	stop, done, cancel := r.stop, r.done, r.cancel
+	r.stop, r.done, r.cancel = nil, nil, nil
+	r.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+
+	close(stop)
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wake makes the running relay poll the outbox table immediately instead of waiting for the next interval,
+// it does not block
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// poll publishes batches until there are no pending messages
+func (r *Relay) poll(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.Process(ctx)
+		if err != nil {
+			r.logger.Warn("outbox: cannot process messages", "error", err.Error())
+			return
+		}
+		if n < r.batchSize {
+			return
+		}
+	}
+}
+
+// Process publishes one batch of pending messages within a transaction and returns the number of processed
+// messages. Rows are locked with FOR UPDATE SKIP LOCKED, so several relays can process the outbox concurrently.
+// Messages are delivered at least once: if the transaction is not committed, they are published again
+func (r *Relay) Process(ctx context.Context) (int, error) {
+	var n int
+	err := r.outbox.wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+		msgs, err := r.fetch(ctx)
+		if err != nil {
+			return err
+		}
+		n = len(msgs)
+		for _, msg := range msgs {
+			if err = r.publish(ctx, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (r *Relay) fetch(ctx context.Context) ([]Message, error) {
+	o := r.outbox
+	query := fmt.Sprintf(
+		"SELECT id, topic, msg_key, payload, headers, attempts, created_at FROM %s"+
+			" WHERE status = %s AND next_attempt_at <= %s ORDER BY id LIMIT %d FOR UPDATE SKIP LOCKED",
+		o.table,
+		o.dialect.placeholder(1),
+		o.dialect.placeholder(2),
+		r.batchSize,
+	)
+	rows, err := o.wrapper.Connection(ctx).QueryContext(ctx, query, StatusPending, o.now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("outbox: cannot fetch messages: %w", err)
+	}
+	defer rows.Close()
+
+	var msgs []Message
+	for rows.Next() {
+		var (
+			msg     Message
+			headers *string
+		)
+		if err = rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &msg.Payload, &headers, &msg.Attempts, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("outbox: cannot scan message: %w", err)
+		}
+		if msg.Headers, err = decodeHeaders(headers); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("outbox: cannot fetch messages: %w", err)
+	}
+	return msgs, nil
+}
+
+// publish publishes the message and updates its status, only database errors are returned
+func (r *Relay) publish(ctx context.Context, msg Message) error {
+	o := r.outbox
+	conn := o.wrapper.Connection(ctx)
+	now := o.now().UTC()
+
+	pubErr := r.publisher.Publish(ctx, msg)
+	if pubErr == nil {
+		query := fmt.Sprintf(
+			"UPDATE %s SET status = %s, delivered_at = %s, last_error = NULL WHERE id = %s",
+			o.table,
+			o.dialect.placeholder(1),
+			o.dialect.placeholder(2),
+			o.dialect.placeholder(3),
+		)
+		if _, err := conn.ExecContext(ctx, query, StatusDelivered, now, msg.ID); err != nil {
+			return fmt.Errorf("outbox: cannot mark message %d as delivered: %w", msg.ID, err)
+		}
+		return nil
+	}
+
+	msg.Attempts++
+	status := StatusPending
+	if msg.Attempts >= r.maxAttempts {
+		status = StatusDead
+	}
+	query := fmt.Sprintf(
+		"UPDATE %s SET status = %s, attempts = %s, next_attempt_at = %s, last_error = %s WHERE id = %s",
+		o.table,
+		o.dialect.placeholder(1),
+		o.dialect.placeholder(2),
+		o.dialect.placeholder(3),
+		o.dialect.placeholder(4),
+		o.dialect.placeholder(5),
+	)
+	_, err := conn.ExecContext(ctx, query, status, msg.Attempts, now.Add(r.retryDelay(msg.Attempts)), pubErr.Error(), msg.ID)
+	if err != nil {
+		return fmt.Errorf("outbox: cannot update message %d: %w", msg.ID, err)
+	}
+
+	if status == StatusDead {
+		r.logger.Warn("outbox: message is moved to dead letters", "id", msg.ID, "topic", msg.Topic, "attempts", msg.Attempts, "error", pubErr.Error())
+		if r.deadLetter != nil {
+			// the handler is called once the status is actually saved
+			if err = sqltx.AfterCommit(ctx, func(ctx context.Context) error {
+				r.deadLetter(ctx, msg, pubErr)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	r.logger.Warn("outbox: cannot publish message", "id", msg.ID, "topic", msg.Topic, "attempts", msg.Attempts, "error", pubErr.Error())
+	return nil
+}
+
+// retryDelay returns the delay after the given number of failed attempts
+func (r *Relay) retryDelay(attempts int) time.Duration {
+	d := r.retryInitial
+	for i := 1; i < attempts && d < r.retryMax; i++ {
+		d *= 2
+	}
+	if d > r.retryMax {
+		d = r.retryMax
+	}
+	return d
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Warn(string, ...any) {}
@@ -0,0 +1,247 @@
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ErrNotFound is returned by QueryOne when the query returns no rows. It wraps sql.ErrNoRows,
+// so errorsx maps it to the not found error
+var ErrNotFound = fmt.Errorf("sqltx: record is not found: %w", sql.ErrNoRows)
+
+// QueryOne returns the first row of the query result mapped to T, or ErrNotFound if there are no rows.
+// T is either a struct, which fields are mapped to columns, or a type scanned from a single column, e.g. int64.
+// Struct fields are mapped to columns by db tag or by the snake case name of the field (UserID -> user_id),
+// fields of embedded structs are mapped as the fields of the outer struct, `db:"-"` skips the field.
+// Fields of unexported embedded struct pointers are not mapped, since they cannot be allocated.
+// NULL is scanned as the zero value, use pointer fields or sql.Null* types in order to distinguish it.
+// The query is run using the transaction of the context if any
+func QueryOne[T any](ctx context.Context, w Wrapper, query string, args ...any) (T, error) {
+	var zero T
+	rows, err := w.Connection(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return zero, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return zero, err
+		}
+		return zero, ErrNotFound
+	}
+	scan, err := newScanner[T](rows)
+	if err != nil {
+		return zero, err
+	}
+	v, err := scan()
+	if err != nil {
+		return zero, err
+	}
+	return v, rows.Close()
+}
+
+// QueryAll returns all rows of the query result mapped to T, see QueryOne for the mapping rules.
+// The result is empty (not nil) if there are no rows
+func QueryAll[T any](ctx context.Context, w Wrapper, query string, args ...any) ([]T, error) {
+	rows, err := w.Connection(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]T, 0)
+	var scan func() (T, error)
+	for rows.Next() {
+		if scan == nil {
+			if scan, err = newScanner[T](rows); err != nil {
+				return nil, err
+			}
+		}
+		v, err := scan()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Exec runs the statement and returns the number of affected rows
+func Exec(ctx context.Context, w Wrapper, query string, args ...any) (int64, error) {
+	res, err := w.Connection(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// newScanner returns the function scanning the current row into T
+func newScanner[T any](rows *sql.Rows) (func() (T, error), error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	if !isStruct(typ) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("sqltx: %d columns cannot be scanned into %s", len(columns), typ)
+		}
+		return func() (T, error) {
+			var v T
+			dest, assign := destination(reflect.ValueOf(&v).Elem())
+			if err := rows.Scan(dest); err != nil {
+				return v, err
+			}
+			if assign != nil {
+				assign()
+			}
+			return v, nil
+		}, nil
+	}
+
+	fields := structFields(typ)
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := fields[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("sqltx: column %q is not mapped to any field of %s", column, typ)
+		}
+		indexes[i] = index
+	}
+
+	return func() (T, error) {
+		var v T
+		rv := reflect.ValueOf(&v).Elem()
+		dest := make([]any, len(indexes))
+		assigns := make([]func(), 0, len(indexes))
+		for i, index := range indexes {
+			var assign func()
+			dest[i], assign = destination(fieldByIndex(rv, index))
+			if assign != nil {
+				assigns = append(assigns, assign)
+			}
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return v, err
+		}
+		for _, assign := range assigns {
+			assign()
+		}
+		return v, nil
+	}, nil
+}
+
+// isStruct reports whether the fields of the type are mapped to columns
+func isStruct(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Struct && typ != timeType && !reflect.PointerTo(typ).Implements(scannerType)
+}
+
+// destination returns the scan destination of the value. NULL is scanned as the zero value into values which
+// are neither pointers nor scanners, so they are scanned through a pointer assigned afterwards
+func destination(v reflect.Value) (any, func()) {
+	if v.Kind() == reflect.Pointer || v.Addr().Type().Implements(scannerType) {
+		return v.Addr().Interface(), nil
+	}
+	ptr := reflect.New(reflect.PointerTo(v.Type()))
+	return ptr.Interface(), func() {
+		if p := ptr.Elem(); !p.IsNil() {
+			v.Set(p.Elem())
+		}
+	}
+}
+
+// fieldByIndex returns the nested field allocating nil embedded pointers
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldsCache keeps column to field index mapping of struct types
+var fieldsCache sync.Map
+
+func structFields(typ reflect.Type) map[string][]int {
+	if fields, ok := fieldsCache.Load(typ); ok {
+		return fields.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	collectFields(typ, nil, fields)
+	fieldsCache.Store(typ, fields)
+	return fields
+}
+
+func collectFields(typ reflect.Type, parent []int, fields map[string][]int) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag, tagged := f.Tag.Lookup("db")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		index := append(append([]int(nil), parent...), i)
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && !tagged && isStruct(ft) {
+			// the unexported pointer cannot be allocated, so it is skipped like encoding/json does
+			if !f.IsExported() && f.Type.Kind() == reflect.Pointer {
+				continue
+			}
+			collectFields(ft, index, fields)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		name := tag
+		if !tagged || name == "" {
+			name = snakeCase(f.Name)
+		}
+		name = strings.ToLower(name)
+		// fields of the outer struct win over the fields of embedded structs
+		if _, ok := fields[name]; !ok || len(fields[name]) > len(index) {
+			fields[name] = index
+		}
+	}
+}
+
+// snakeCase converts the field name to snake case, e.g. UserID -> user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
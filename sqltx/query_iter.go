@@ -0,0 +1,51 @@
+//go:build go1.23
+
+package sqltx
+
+import (
+	"context"
+	"iter"
+)
+
+// QueryIter returns the iterator over the rows of the query result mapped to T, see QueryOne for the mapping rules.
+// The query is run when the iteration begins and the rows are closed when it ends. If an error occurs,
+// it is yielded with the zero value of T and the iteration stops
+//
+//	for user, err := range sqltx.QueryIter[User](ctx, wrapper, "SELECT * FROM users") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func QueryIter[T any](ctx context.Context, w Wrapper, query string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		rows, err := w.Connection(ctx).QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		var scan func() (T, error)
+		for rows.Next() {
+			if scan == nil {
+				if scan, err = newScanner[T](rows); err != nil {
+					yield(zero, err)
+					return
+				}
+			}
+			v, err := scan()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build go1.23
+
+package sqltx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/velmie/x/sqltx"
+)
+
+func TestQueryIter(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+	errRow := errors.New("row error")
+
+	mock.ExpectQuery("SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3)).
+		RowsWillBeClosed()
+	mock.ExpectQuery("SELECT id FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).RowError(1, errRow))
+
+	var ids []int64
+	for id, err := range QueryIter[int64](context.Background(), wrapper, "SELECT id FROM users") {
+		require.NoError(t, err)
+		ids = append(ids, id)
+		if id == 2 {
+			break
+		}
+	}
+	require.Equal(t, []int64{1, 2}, ids)
+
+	var errs []error
+	for _, err := range QueryIter[int64](context.Background(), wrapper, "SELECT id FROM users") {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 2)
+	require.NoError(t, errs[0])
+	require.ErrorIs(t, errs[1], errRow)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,168 @@
+package sqltx_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/velmie/x/sqltx"
+)
+
+type Audit struct {
+	CreatedAt time.Time
+	UpdatedBy *string `db:"updated_by"`
+}
+
+type Version struct {
+	Version int
+}
+
+type user struct {
+	Audit
+	*Version
+	UserID   int64  `db:"id"`
+	Email    string `db:"email"`
+	Nickname string
+	Age      sql.NullInt64
+	Ignored  string `db:"-"`
+	internal string
+}
+
+var userColumns = []string{"id", "email", "nickname", "age", "created_at", "updated_by", "version"}
+
+func TestQueryOne(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT (.+) FROM users").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(1, "john@example.com", nil, nil, createdAt, "admin", 3))
+
+	u, err := QueryOne[user](context.Background(), wrapper, "SELECT * FROM users WHERE id = ?", int64(1))
+
+	require.NoError(t, err)
+	require.Equal(t, int64(1), u.UserID)
+	require.Equal(t, "john@example.com", u.Email)
+	require.Equal(t, "", u.Nickname, "NULL is scanned as the zero value")
+	require.False(t, u.Age.Valid)
+	require.Equal(t, createdAt, u.CreatedAt)
+	require.Equal(t, "admin", *u.UpdatedBy)
+	require.Equal(t, 3, u.Version.Version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryOne_NotFound(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(userColumns))
+
+	_, err := QueryOne[user](context.Background(), wrapper, "SELECT * FROM users WHERE id = ?", 1)
+
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryOne_Scalar(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	mock.ExpectQuery("SELECT MAX").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectQuery("SELECT MIN").WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(nil))
+
+	count, err := QueryOne[int64](context.Background(), wrapper, "SELECT COUNT(*) FROM users")
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+
+	maxAge, err := QueryOne[*int64](context.Background(), wrapper, "SELECT MAX(age) FROM users")
+	require.NoError(t, err)
+	require.Nil(t, maxAge)
+
+	minAge, err := QueryOne[sql.NullInt64](context.Background(), wrapper, "SELECT MIN(age) FROM users")
+	require.NoError(t, err)
+	require.False(t, minAge.Valid)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryOne_UnmappedColumn(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, "secret"))
+
+	_, err := QueryOne[user](context.Background(), wrapper, "SELECT id, password FROM users")
+
+	require.ErrorContains(t, err, `column "password" is not mapped`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryOne_UnexportedEmbeddedPointer(t *testing.T) {
+	type base struct {
+		ID int64
+	}
+	type row struct {
+		*base
+		Name string
+	}
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectQuery("SELECT name").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("john"))
+	mock.ExpectQuery("SELECT id").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "john"))
+
+	r, err := QueryOne[row](context.Background(), wrapper, "SELECT name FROM users")
+	require.NoError(t, err)
+	require.Equal(t, "john", r.Name)
+	require.Nil(t, r.base)
+
+	_, err = QueryOne[row](context.Background(), wrapper, "SELECT id, name FROM users")
+	require.ErrorContains(t, err, `column "id" is not mapped`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryAll(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, email FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com").AddRow(2, "b@example.com"))
+	mock.ExpectQuery("SELECT id, email FROM users").WillReturnRows(sqlmock.NewRows([]string{"id", "email"}))
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		users, err := QueryAll[user](ctx, wrapper, "SELECT id, email FROM users")
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+		require.Equal(t, "b@example.com", users[1].Email)
+
+		users, err = QueryAll[user](ctx, wrapper, "SELECT id, email FROM users WHERE id > ?", 2)
+		require.NoError(t, err)
+		require.NotNil(t, users)
+		require.Empty(t, users)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExec(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := Exec(context.Background(), wrapper, "DELETE FROM users WHERE age < ?", 18)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
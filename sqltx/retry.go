@@ -0,0 +1,137 @@
+package sqltx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+var (
+	// ErrNestedRetry is returned when the retry is requested within the outer transaction
+	ErrNestedRetry = errors.New("sqltx: transaction cannot be retried within the outer transaction")
+	// ErrNoClassifier is returned when the retry policy has no classifier, retryable errors depend on the driver
+	ErrNoClassifier = errors.New("sqltx: retry policy classifier is not set")
+)
+
+// Classifier decides whether the transaction failed with the error is worth retrying
+type Classifier interface {
+	Retryable(err error) bool
+}
+
+// ClassifierFunc allows to use an ordinary function as Classifier
+type ClassifierFunc func(err error) bool
+
+func (f ClassifierFunc) Retryable(err error) bool {
+	return f(err)
+}
+
+// sqlStateError is implemented by errors of Postgres drivers (pgx, lib/pq)
+type sqlStateError interface {
+	SQLState() string
+}
+
+// Postgres SQLSTATE codes of the errors caused by concurrent transactions
+const (
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+)
+
+// SQLStateClassifier considers retryable the errors having one of the given SQLSTATE codes.
+// The error must implement SQLState() string method as errors of pgx and lib/pq drivers do
+func SQLStateClassifier(codes ...string) Classifier {
+	return ClassifierFunc(func(err error) bool {
+		var se sqlStateError
+		if !errors.As(err, &se) {
+			return false
+		}
+		state := se.SQLState()
+		for _, code := range codes {
+			if state == code {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// PostgresClassifier considers retryable serialization failures and deadlocks
+var PostgresClassifier = SQLStateClassifier(SQLStateSerializationFailure, SQLStateDeadlockDetected)
+
+// RetryPolicy specifies how failed transactions are retried, zero values are replaced with defaults
+// except Classifier which is required
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one
+	MaxAttempts int
+	// Initial is the delay before the first retry
+	Initial time.Duration
+	// Max limits the delay
+	Max time.Duration
+	// Multiplier is the factor the delay is multiplied by after each retry
+	Multiplier float64
+	// Jitter is the fraction of the delay which is randomized, e.g. 0.2 means ±20%. There is no jitter if it is zero
+	Jitter float64
+	// Classifier decides whether the error is retryable, e.g. PostgresClassifier.
+	// See svc/sqlconnection/mysql package for the MySQL classifier
+	Classifier Classifier
+}
+
+// DefaultRetryPolicy retries the transaction twice with 50ms and 100ms delays (±20%), the classifier must be set
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Initial:     50 * time.Millisecond,
+	Max:         time.Second,
+	Multiplier:  2,
+	Jitter:      0.2,
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+	if p.Initial <= 0 {
+		p.Initial = DefaultRetryPolicy.Initial
+	}
+	if p.Max <= 0 {
+		p.Max = DefaultRetryPolicy.Max
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DefaultRetryPolicy.Multiplier
+	}
+	return p
+}
+
+// delay returns the delay before the given retry (starting from 1)
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := float64(p.Initial) * math.Pow(p.Multiplier, float64(retry-1))
+	if d > float64(p.Max) {
+		d = float64(p.Max)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// withRetry runs the transaction until it succeeds, fails with not retryable error or attempts are exhausted
+func (g *DefaultWrapper) withRetry(ctx context.Context, policy RetryPolicy, run func() error) error {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Classifier.Retryable(err) {
+			return err
+		}
+
+		delay := policy.delay(attempt)
+		g.logger.Warn("sqltx: retrying transaction", "attempt", attempt, "delay", delay.String(), "error", err.Error())
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
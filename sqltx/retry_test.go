@@ -0,0 +1,151 @@
+package sqltx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/velmie/x/sqltx"
+)
+
+type pgError struct {
+	code string
+}
+
+func (e *pgError) Error() string {
+	return "pg error " + e.code
+}
+
+func (e *pgError) SQLState() string {
+	return e.code
+}
+
+var fastRetry = RetryPolicy{
+	MaxAttempts: 3,
+	Initial:     time.Millisecond,
+	Max:         time.Millisecond,
+	Classifier:  PostgresClassifier,
+}
+
+func TestWithTransaction_Retry(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE test").WillReturnError(&pgError{code: SQLStateDeadlockDetected})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE test").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(&pgError{code: SQLStateSerializationFailure})
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE test").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	attempts := 0
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		_, err := wrapper.Connection(ctx).ExecContext(ctx, "UPDATE test SET a = 1")
+		return err
+	}, WithRetry(fastRetry))
+
+	require.NoError(t, err)
+	require.Equal(t, 3, attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_RetryExhausted(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	for i := 0; i < fastRetry.MaxAttempts; i++ {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	errDeadlock := &pgError{code: SQLStateDeadlockDetected}
+	attempts := 0
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return errDeadlock
+	}, WithRetry(fastRetry))
+
+	require.ErrorIs(t, err, errDeadlock)
+	require.Equal(t, fastRetry.MaxAttempts, attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_RetryClassifier(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+	errBusy := errors.New("busy")
+	policy := fastRetry
+	policy.Classifier = ClassifierFunc(func(err error) bool {
+		return errors.Is(err, errBusy)
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	attempts := 0
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errBusy
+		}
+		// not retryable
+		return &pgError{code: SQLStateDeadlockDetected}
+	}, WithRetry(policy))
+
+	require.Error(t, err)
+	require.Equal(t, 2, attempts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_RetryNested(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			t.Fatal("nested function must not be called")
+			return nil
+		}, WithRetry(fastRetry))
+	})
+
+	require.ErrorIs(t, err, ErrNestedRetry)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_RetryWithoutClassifier(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	called := false
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	}, WithRetry(RetryPolicy{MaxAttempts: 3}))
+
+	require.ErrorIs(t, err, ErrNoClassifier)
+	require.False(t, called)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyOptions(t *testing.T) {
+	require.Equal(t, Options{}, ApplyOptions())
+
+	policy := RetryPolicy{MaxAttempts: 2}
+	o := ApplyOptions(ReadOnly(), WithIsolationLevel(sql.LevelSerializable), WithRetry(policy))
+	require.Equal(t, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelSerializable}, o.TxOptions)
+	require.Equal(t, &policy, o.Retry)
+}
@@ -0,0 +1,278 @@
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReplicaCooldown is the time the failed replica is not used unless WithReplicaCooldown is specified
+const DefaultReplicaCooldown = 10 * time.Second
+
+type readOnlyKey struct{}
+
+type writesKey struct{}
+
+// ReadOnlyContext marks the context as read-only, so RoutingWrapper routes queries outside of transactions
+// to replicas
+func ReadOnlyContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// ReadYourWrites makes RoutingWrapper route all queries with the returned context (and contexts derived from it)
+// to the primary once a write is done, so the changes are visible despite replication lag.
+// It is meant to be called once per request, e.g. in HTTP middleware
+func ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, &atomic.Bool{})
+}
+
+func isReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+func isPinned(ctx context.Context) bool {
+	written, ok := ctx.Value(writesKey{}).(*atomic.Bool)
+	return ok && written.Load()
+}
+
+func pin(ctx context.Context) {
+	if written, ok := ctx.Value(writesKey{}).(*atomic.Bool); ok {
+		written.Store(true)
+	}
+}
+
+// RoutingOption configures RoutingWrapper
+type RoutingOption func(w *RoutingWrapper)
+
+// WithReplicaCooldown sets the time the replica is not used after a connection failure
+func WithReplicaCooldown(d time.Duration) RoutingOption {
+	return func(w *RoutingWrapper) {
+		if d > 0 {
+			w.cooldown = d
+		}
+	}
+}
+
+// WithWrapperOptions applies the options to the wrappers of the primary and the replicas
+func WithWrapperOptions(opts ...WrapperOption) RoutingOption {
+	return func(w *RoutingWrapper) {
+		w.wrapperOpts = append(w.wrapperOpts, opts...)
+	}
+}
+
+type replica struct {
+	db      *sql.DB
+	wrapper *DefaultWrapper
+	// downUntil is unix nanoseconds until the replica is considered unavailable
+	downUntil atomic.Int64
+}
+
+// RoutingWrapper implements Wrapper routing read-only queries to replicas:
+//
+//   - queries outside of transactions with the context marked by ReadOnlyContext go to a replica;
+//   - transactions started with ReadOnly option go to a replica;
+//   - everything else goes to the primary;
+//   - if the context is created by ReadYourWrites, everything goes to the primary after the first write
+//     (a committed read-write transaction, Exec or Prepare outside of transactions).
+//
+// Replicas are selected with round-robin, the replica failed with a connection error is skipped during the cooldown.
+// The primary is used if there is no available replica
+type RoutingWrapper struct {
+	primary     *DefaultWrapper
+	replicas    []*replica
+	logger      Logger
+	cooldown    time.Duration
+	wrapperOpts []WrapperOption
+	next        atomic.Uint64
+	now         func() time.Time
+	checkMu     sync.Mutex
+}
+
+// NewRoutingWrapper is RoutingWrapper constructor
+func NewRoutingWrapper(primary *sql.DB, replicas []*sql.DB, logger Logger, opts ...RoutingOption) *RoutingWrapper {
+	w := &RoutingWrapper{
+		logger:   logger,
+		cooldown: DefaultReplicaCooldown,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.primary = NewDefaultWrapper(primary, logger, w.wrapperOpts...)
+	for _, db := range replicas {
+		w.replicas = append(w.replicas, &replica{db: db, wrapper: NewDefaultWrapper(db, logger, w.wrapperOpts...)})
+	}
+	return w
+}
+
+func (w *RoutingWrapper) WithTransaction(ctx context.Context, f func(ctx context.Context) error, opts ...Option) error {
+	nested := Depth(ctx) > 0
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	readOnly := o.tx != nil && o.tx.ReadOnly
+
+	// nested calls are handled by the wrapper of the outer transaction, the transaction is shared via context
+	if !nested && readOnly && !isPinned(ctx) {
+		if r := w.pick(); r != nil {
+			err := r.wrapper.WithTransaction(ctx, f, opts...)
+			w.observe(r, err)
+			return err
+		}
+	}
+
+	err := w.primary.WithTransaction(ctx, f, opts...)
+	if err == nil && !nested && !readOnly {
+		pin(ctx)
+	}
+	return err
+}
+
+func (w *RoutingWrapper) Connection(ctx context.Context) Connection {
+	if Depth(ctx) > 0 {
+		return w.primary.Connection(ctx)
+	}
+	if isReadOnly(ctx) && !isPinned(ctx) {
+		if r := w.pick(); r != nil {
+			return &replicaConnection{Connection: r.db, observe: func(err error) { w.observe(r, err) }}
+		}
+	}
+	return &primaryConnection{Connection: w.primary.db, ctx: ctx}
+}
+
+// CheckReplicas pings the replicas and updates their availability, e.g. it can be scheduled with tickrx.
+// Without checks, the replica is used again once the cooldown is expired
+func (w *RoutingWrapper) CheckReplicas(ctx context.Context) {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+	for _, r := range w.replicas {
+		err := r.db.PingContext(ctx)
+		if err != nil {
+			w.markDown(r, err)
+			continue
+		}
+		r.downUntil.Store(0)
+	}
+}
+
+// pick returns the next available replica or nil
+func (w *RoutingWrapper) pick() *replica {
+	n := len(w.replicas)
+	if n == 0 {
+		return nil
+	}
+	now := w.now().UnixNano()
+	start := w.next.Add(1) - 1
+	for i := 0; i < n; i++ {
+		r := w.replicas[(start+uint64(i))%uint64(n)]
+		if r.downUntil.Load() <= now {
+			return r
+		}
+	}
+	return nil
+}
+
+func (w *RoutingWrapper) observe(r *replica, err error) {
+	if isConnectionError(err) {
+		w.markDown(r, err)
+	}
+}
+
+func (w *RoutingWrapper) markDown(r *replica, err error) {
+	r.downUntil.Store(w.now().Add(w.cooldown).UnixNano())
+	w.logger.Warn("sqltx: replica is unavailable", "cooldown", w.cooldown.String(), "error", err.Error())
+}
+
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr)
+}
+
+// replicaConnection reports connection errors, so the replica is not used during the cooldown
+type replicaConnection struct {
+	Connection
+	observe func(err error)
+}
+
+func (c *replicaConnection) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := c.Connection.ExecContext(ctx, query, args...)
+	c.observe(err)
+	return res, err
+}
+
+func (c *replicaConnection) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := c.Connection.PrepareContext(ctx, query)
+	c.observe(err)
+	return stmt, err
+}
+
+func (c *replicaConnection) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := c.Connection.QueryContext(ctx, query, args...)
+	c.observe(err)
+	return rows, err
+}
+
+func (c *replicaConnection) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := c.Connection.QueryRowContext(ctx, query, args...)
+	c.observe(row.Err())
+	return row
+}
+
+func (c *replicaConnection) QueryRow(query string, args ...any) *sql.Row {
+	row := c.Connection.QueryRow(query, args...)
+	c.observe(row.Err())
+	return row
+}
+
+func (c *replicaConnection) Query(query string, args ...any) (*sql.Rows, error) {
+	rows, err := c.Connection.Query(query, args...)
+	c.observe(err)
+	return rows, err
+}
+
+func (c *replicaConnection) Exec(query string, args ...any) (sql.Result, error) {
+	res, err := c.Connection.Exec(query, args...)
+	c.observe(err)
+	return res, err
+}
+
+// primaryConnection pins the context to the primary once Exec succeeds. Prepare pins it as well,
+// since the statement might write and its execution is not tracked
+type primaryConnection struct {
+	Connection
+	ctx context.Context
+}
+
+func (c *primaryConnection) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := c.Connection.ExecContext(ctx, query, args...)
+	if err == nil {
+		pin(c.ctx)
+	}
+	return res, err
+}
+
+func (c *primaryConnection) Exec(query string, args ...any) (sql.Result, error) {
+	res, err := c.Connection.Exec(query, args...)
+	if err == nil {
+		pin(c.ctx)
+	}
+	return res, err
+}
+
+func (c *primaryConnection) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := c.Connection.PrepareContext(ctx, query)
+	if err == nil {
+		pin(c.ctx)
+	}
+	return stmt, err
+}
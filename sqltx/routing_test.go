@@ -0,0 +1,207 @@
+package sqltx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/velmie/x/sqltx"
+)
+
+type routingMocks struct {
+	primary  sqlmock.Sqlmock
+	replicas []sqlmock.Sqlmock
+}
+
+func (m *routingMocks) met(t *testing.T) {
+	t.Helper()
+	require.NoError(t, m.primary.ExpectationsWereMet())
+	for _, r := range m.replicas {
+		require.NoError(t, r.ExpectationsWereMet())
+	}
+}
+
+func newRoutingWrapper(t *testing.T, replicas int, opts ...RoutingOption) (*RoutingWrapper, *routingMocks) {
+	t.Helper()
+	newDB := func() (*sql.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = db.Close()
+		})
+		return db, mock
+	}
+
+	primary, primaryMock := newDB()
+	mocks := &routingMocks{primary: primaryMock}
+	var dbs []*sql.DB
+	for i := 0; i < replicas; i++ {
+		db, mock := newDB()
+		dbs = append(dbs, db)
+		mocks.replicas = append(mocks.replicas, mock)
+	}
+	return NewRoutingWrapper(primary, dbs, &noopLogger{}, opts...), mocks
+}
+
+func query(t *testing.T, w Wrapper, ctx context.Context) error {
+	t.Helper()
+	rows, err := w.Connection(ctx).QueryContext(ctx, "SELECT 1")
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func oneRow() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"1"}).AddRow(1)
+}
+
+func TestRoutingWrapper_Connection(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 2)
+	readOnly := ReadOnlyContext(context.Background())
+
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[1].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+
+	require.NoError(t, query(t, w, context.Background()))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, query(t, w, readOnly))
+	}
+	mocks.met(t)
+}
+
+func TestRoutingWrapper_Transaction(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 1)
+	readOnly := ReadOnlyContext(context.Background())
+
+	mocks.replicas[0].ExpectBegin()
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[0].ExpectCommit()
+	mocks.primary.ExpectBegin()
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.primary.ExpectCommit()
+
+	err := w.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return query(t, w, ctx)
+	}, ReadOnly())
+	require.NoError(t, err)
+
+	// the transaction wins over the read-only context
+	err = w.WithTransaction(readOnly, func(ctx context.Context) error {
+		return query(t, w, ctx)
+	})
+	require.NoError(t, err)
+	mocks.met(t)
+}
+
+func TestRoutingWrapper_ReadYourWrites(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 1)
+
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.primary.ExpectExec("UPDATE test").WillReturnResult(sqlmock.NewResult(0, 1))
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.primary.ExpectBegin()
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.primary.ExpectCommit()
+
+	ctx := ReadYourWrites(context.Background())
+	require.NoError(t, query(t, w, ReadOnlyContext(ctx)))
+
+	_, err := w.Connection(ctx).ExecContext(ctx, "UPDATE test SET a = 1")
+	require.NoError(t, err)
+
+	require.NoError(t, query(t, w, ReadOnlyContext(ctx)))
+	err = w.WithTransaction(ctx, func(ctx context.Context) error {
+		return query(t, w, ctx)
+	}, ReadOnly())
+	require.NoError(t, err)
+	mocks.met(t)
+}
+
+func TestRoutingWrapper_ReadYourWritesAfterCommit(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 1)
+
+	mocks.primary.ExpectBegin()
+	mocks.primary.ExpectCommit()
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+
+	ctx := ReadYourWrites(context.Background())
+	require.NoError(t, w.WithTransaction(ctx, func(ctx context.Context) error {
+		return nil
+	}))
+
+	require.NoError(t, query(t, w, ReadOnlyContext(ctx)))
+	// other requests are not affected
+	require.NoError(t, query(t, w, ReadOnlyContext(context.Background())))
+	mocks.met(t)
+}
+
+func TestRoutingWrapper_ReplicaFailure(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 2, WithReplicaCooldown(time.Hour))
+	readOnly := ReadOnlyContext(context.Background())
+	connErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnError(connErr)
+	mocks.replicas[1].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[1].ExpectQuery("SELECT 1").WillReturnError(connErr)
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+
+	require.ErrorIs(t, query(t, w, readOnly), connErr)
+	// the first replica is skipped
+	require.NoError(t, query(t, w, readOnly))
+	require.Error(t, query(t, w, readOnly))
+	// there are no available replicas
+	require.NoError(t, query(t, w, readOnly))
+	mocks.met(t)
+
+	mocks.replicas[0].ExpectPing()
+	mocks.replicas[1].ExpectPing().WillReturnError(connErr)
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+
+	w.CheckReplicas(context.Background())
+	require.NoError(t, query(t, w, readOnly))
+	require.NoError(t, query(t, w, readOnly))
+	mocks.met(t)
+}
+
+func TestRoutingWrapper_ReplicaFailureQueryRow(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 2, WithReplicaCooldown(time.Hour))
+	readOnly := ReadOnlyContext(context.Background())
+	connErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	mocks.replicas[0].ExpectQuery("SELECT 1").WillReturnError(connErr)
+	mocks.replicas[1].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+	mocks.replicas[1].ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+
+	var n int
+	require.ErrorIs(t, w.Connection(readOnly).QueryRowContext(readOnly, "SELECT 1").Scan(&n), connErr)
+	require.NoError(t, w.Connection(readOnly).QueryRowContext(readOnly, "SELECT 1").Scan(&n))
+	// the first replica is skipped
+	require.NoError(t, w.Connection(readOnly).QueryRow("SELECT 1").Scan(&n))
+	mocks.met(t)
+}
+
+func TestRoutingWrapper_ReadYourWritesAfterPrepare(t *testing.T) {
+	w, mocks := newRoutingWrapper(t, 1)
+
+	mocks.primary.ExpectPrepare("UPDATE test")
+	mocks.primary.ExpectQuery("SELECT 1").WillReturnRows(oneRow())
+
+	ctx := ReadYourWrites(context.Background())
+	stmt, err := w.Connection(ctx).PrepareContext(ctx, "UPDATE test SET a = ?")
+	require.NoError(t, err)
+	defer stmt.Close()
+
+	require.NoError(t, query(t, w, ReadOnlyContext(ctx)))
+	mocks.met(t)
+}
@@ -0,0 +1,127 @@
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrConflictingOptions is returned when options of the nested transaction conflict with the outer transaction
+var ErrConflictingOptions = errors.New("sqltx: options conflict with the outer transaction")
+
+// Dialect builds savepoint statements
+type Dialect interface {
+	Savepoint(name string) string
+	RollbackToSavepoint(name string) string
+	ReleaseSavepoint(name string) string
+}
+
+// standardDialect builds savepoint statements defined by the SQL standard
+type standardDialect struct{}
+
+func (standardDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + name
+}
+
+func (standardDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + name
+}
+
+func (standardDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + name
+}
+
+var (
+	// MySQL dialect
+	MySQL Dialect = standardDialect{}
+	// Postgres dialect
+	Postgres Dialect = standardDialect{}
+)
+
+// WrapperOption configures DefaultWrapper
+type WrapperOption func(w *DefaultWrapper)
+
+// WithSavepoints enables nested transactions based on savepoints. A nested WithTransaction call creates
+// a savepoint, the savepoint is rolled back if the function returns an error and released otherwise,
+// so the failure of the nested function does not affect the outer transaction.
+// By default, nested calls join the outer transaction
+func WithSavepoints(d Dialect) WrapperOption {
+	return func(w *DefaultWrapper) {
+		w.dialect = d
+	}
+}
+
+// Depth returns the nesting level of the transaction in the context: 0 means there is no transaction,
+// 1 means the outermost transaction which is committed by the WithTransaction call owning it
+func Depth(ctx context.Context) int {
+	t, ok := ctx.Value(txKey{}).(*transaction)
+	if !ok {
+		return 0
+	}
+	return t.depth
+}
+
+// transaction is shared via context by the outermost and the nested WithTransaction calls
+type transaction struct {
+	tx    *sql.Tx
+	opts  sql.TxOptions
+	depth int
+	hooks *hooks
+}
+
+func (t *transaction) nested() *transaction {
+	return &transaction{tx: t.tx, opts: t.opts, depth: t.depth + 1, hooks: t.hooks}
+}
+
+// checkOptions verifies the options of the nested transaction are satisfied by the outer transaction
+func (t *transaction) checkOptions(opts *sql.TxOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.ReadOnly && !t.opts.ReadOnly {
+		return fmt.Errorf("%w: read-only transaction is requested within read-write transaction", ErrConflictingOptions)
+	}
+	if opts.Isolation != sql.LevelDefault && opts.Isolation != t.opts.Isolation {
+		return fmt.Errorf(
+			"%w: isolation level %s is requested within transaction with isolation level %s",
+			ErrConflictingOptions,
+			opts.Isolation,
+			t.opts.Isolation,
+		)
+	}
+	return nil
+}
+
+// withSavepoint runs the function within the savepoint of the outer transaction
+func (g *DefaultWrapper) withSavepoint(ctx context.Context, t *transaction, f func(ctx context.Context) error) (err error) {
+	name := fmt.Sprintf("sqltx_%d", t.depth)
+	if _, err = t.tx.ExecContext(ctx, g.dialect.Savepoint(name)); err != nil {
+		return fmt.Errorf("sqltx: savepoint error: %w", err)
+	}
+
+	commitHooks, rollbackHooks := t.hooks.mark()
+	rollback := func() {
+		if _, rbErr := t.tx.ExecContext(ctx, g.dialect.RollbackToSavepoint(name)); rbErr != nil {
+			g.logger.Warn("sqltx: rollback to savepoint error", "savepoint", name, "error", rbErr.Error())
+		}
+		// changes made within the savepoint are discarded, so hooks registered within it are not relevant anymore
+		g.runHooks(ctx, "rollback", t.hooks.cut(commitHooks, rollbackHooks))
+	}
+
+	defer func() {
+		if perr := recover(); perr != nil {
+			rollback()
+			err = fmt.Errorf("panic recovered:\n%g\n%s", perr, stackTrace())
+		}
+	}()
+
+	if err = f(context.WithValue(ctx, txKey{}, t)); err != nil {
+		rollback()
+		return err
+	}
+	if _, err = t.tx.ExecContext(ctx, g.dialect.ReleaseSavepoint(name)); err != nil {
+		return fmt.Errorf("sqltx: release savepoint error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package sqltx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/velmie/x/sqltx"
+)
+
+func TestWithTransaction_SavepointReleased(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{}, WithSavepoints(MySQL))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO test").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		require.Equal(t, 1, Depth(ctx))
+		return wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			require.Equal(t, 2, Depth(ctx))
+			_, err := wrapper.Connection(ctx).ExecContext(ctx, "INSERT INTO test VALUES (1)")
+			return err
+		})
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_SavepointRolledBack(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{}, WithSavepoints(Postgres))
+	errInner := errors.New("inner failure")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO test").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		err := wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			return errInner
+		})
+		require.ErrorIs(t, err, errInner)
+
+		err = wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			panic("test panic")
+		})
+		require.ErrorContains(t, err, "panic recovered")
+
+		// the outer transaction is still usable
+		_, err = wrapper.Connection(ctx).ExecContext(ctx, "INSERT INTO test VALUES (1)")
+		return err
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_NestedJoinDepth(t *testing.T) {
+	db, mock := testDBWithMock(t)
+	wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	require.Equal(t, 0, Depth(context.Background()))
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			require.Equal(t, 2, Depth(ctx))
+			return nil
+		})
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTransaction_ConflictingOptions(t *testing.T) {
+	testCases := []struct {
+		name  string
+		outer []Option
+		inner []Option
+		err   error
+	}{
+		{
+			name:  "read-only within read-write",
+			inner: []Option{ReadOnly()},
+			err:   ErrConflictingOptions,
+		},
+		{
+			name:  "isolation level differs",
+			outer: []Option{WithIsolationLevel(sql.LevelReadCommitted)},
+			inner: []Option{WithIsolationLevel(sql.LevelSerializable)},
+			err:   ErrConflictingOptions,
+		},
+		{
+			name:  "read-only within read-only",
+			outer: []Option{ReadOnly(), WithIsolationLevel(sql.LevelSerializable)},
+			inner: []Option{ReadOnly(), WithIsolationLevel(sql.LevelSerializable)},
+		},
+		{
+			name:  "default options within read-only",
+			outer: []Option{ReadOnly()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := testDBWithMock(t)
+			wrapper := NewDefaultWrapper(db, &noopLogger{})
+
+			mock.ExpectBegin()
+			if tc.err != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+
+			err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+				return wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+					return nil
+				}, tc.inner...)
+			}, tc.outer...)
+
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
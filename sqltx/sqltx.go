@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+
+	"github.com/velmie/x/sqltx/internal/session"
 )
 
 // txKey used as a key for context in order to wrap database transaction
@@ -38,42 +40,89 @@ type Logger interface {
 
 // DefaultWrapper implements Wrapper with Connection
 type DefaultWrapper struct {
-	db     *sql.DB
-	logger Logger
+	db               *sql.DB
+	logger           Logger
+	dialect          Dialect
+	hookErrorHandler HookErrorHandler
 }
 
 // NewDefaultWrapper is DefaultWrapper constructor
-func NewDefaultWrapper(db *sql.DB, logger Logger) *DefaultWrapper {
-	return &DefaultWrapper{db: db, logger: logger}
+func NewDefaultWrapper(db *sql.DB, logger Logger, opts ...WrapperOption) *DefaultWrapper {
+	w := &DefaultWrapper{db: db, logger: logger}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
-func (g *DefaultWrapper) WithTransaction(ctx context.Context, f func(ctx context.Context) error, opts ...Option) (err error) {
-	_, alreadyInTx := ctx.Value(txKey{}).(Connection)
-	// if transaction already started then just pass existing context (nested transaction)
-	// it ignores options
-	if alreadyInTx {
-		return f(ctx)
+func (g *DefaultWrapper) WithTransaction(ctx context.Context, f func(ctx context.Context) error, opts ...Option) error {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
 	}
 
-	var txOpts *sql.TxOptions
-	if len(opts) > 0 {
-		txOpts = &sql.TxOptions{}
+	if outer, ok := ctx.Value(txKey{}).(*transaction); ok {
+		if o.retry != nil {
+			return ErrNestedRetry
+		}
+		// options of the nested transaction must be satisfied by the outer one
+		if err := outer.checkOptions(o.tx); err != nil {
+			return err
+		}
+		t := outer.nested()
+		if g.dialect != nil {
+			return g.withSavepoint(ctx, t, f)
+		}
+		// if savepoints are not enabled then just join the outer transaction
+		return f(context.WithValue(ctx, txKey{}, t))
 	}
-	for _, opt := range opts {
-		opt(txOpts)
+
+	if o.retry != nil {
+		if o.retry.Classifier == nil {
+			return ErrNoClassifier
+		}
+		return g.withRetry(ctx, *o.retry, func() error {
+			return g.transaction(ctx, f, o.tx)
+		})
 	}
-	tx, err := g.db.BeginTx(ctx, txOpts)
+	return g.transaction(ctx, f, o.tx)
+}
+
+// transaction runs the function within the new transaction
+func (g *DefaultWrapper) transaction(ctx context.Context, f func(ctx context.Context) error, txOpts *sql.TxOptions) (err error) {
+	conn, err := g.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, txOpts)
 	if err != nil {
+		_ = conn.Close()
 		return err
 	}
-	c := context.WithValue(ctx, txKey{}, tx)
+	t := &transaction{tx: tx, depth: 1, hooks: &hooks{}}
+	if txOpts != nil {
+		t.opts = *txOpts
+	}
+	cleanups := &session.Cleanups{}
+	c := session.WithCleanups(context.WithValue(ctx, txKey{}, t), cleanups)
+
+	// the session is cleaned up and the connection is returned to the pool before the hooks are called,
+	// since hooks might do network I/O
+	finish := func(stage string, hooks []Hook) {
+		for _, cErr := range cleanups.Run(context.WithoutCancel(ctx), conn) {
+			g.handleHookError(ctx, fmt.Errorf("sqltx: session cleanup error: %w", cErr))
+		}
+		_ = conn.Close()
+		g.runHooks(ctx, stage, hooks)
+	}
 
 	defer func() {
 		if perr := recover(); perr != nil {
 			rbErr := tx.Rollback()
 			if rbErr != nil {
-				g.logger.Warn("sqltx: transaction rollback error: " + rbErr.Error())
+				g.logger.Warn("sqltx: transaction rollback error", "error", rbErr.Error())
 			}
+			finish("rollback", t.hooks.rolledBack())
 
 			err = fmt.Errorf("panic recovered:\n%g\n%s", perr, stackTrace())
 		}
@@ -83,30 +132,34 @@ func (g *DefaultWrapper) WithTransaction(ctx context.Context, f func(ctx context
 	default:
 	case <-ctx.Done():
 		// if context is canceled then transaction is already rolled back
+		_ = conn.Close()
 		return ctx.Err()
 	}
 	err = f(c)
 	if err != nil {
 		rbErr := tx.Rollback()
 		if rbErr != nil && strings.Contains(err.Error(), "context canceled") {
-			g.logger.Warn("sqltx: transaction rollback error: ", rbErr.Error())
+			g.logger.Warn("sqltx: transaction rollback error", "error", rbErr.Error())
 		}
+		finish("rollback", t.hooks.rolledBack())
 		return err
 	}
 
 	cErr := tx.Commit()
 	if cErr != nil {
+		finish("rollback", t.hooks.rolledBack())
 		return fmt.Errorf("sqltx: transaction commit error: %w", cErr)
 	}
+	finish("commit", t.hooks.committed())
 	return err
 }
 
 func (g *DefaultWrapper) Connection(ctx context.Context) Connection {
-	tx, ok := ctx.Value(txKey{}).(Connection)
+	t, ok := ctx.Value(txKey{}).(*transaction)
 	if !ok {
 		return g.db
 	}
-	return tx
+	return t.tx
 }
 
 func stackTrace() string {
@@ -0,0 +1,72 @@
+package sqltxtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/velmie/x/sqltx"
+)
+
+// errRollback makes the wrapper roll the test transaction back
+var errRollback = errors.New("sqltxtest: test transaction is rolled back")
+
+// AssertDepth reports the test failure if the nesting level of the transaction in the context differs from depth
+func AssertDepth(t testing.TB, ctx context.Context, depth int) bool {
+	t.Helper()
+	if got := sqltx.Depth(ctx); got != depth {
+		t.Errorf("expected transaction depth %d, got %d", depth, got)
+		return false
+	}
+	return true
+}
+
+// RollbackTx begins the transaction against the real database which is rolled back when the test finishes,
+// so changes made by the test are not visible to other tests. The returned context holds the transaction and
+// the returned wrapper runs nested transactions within it, pass sqltx.WithSavepoints in order to roll back
+// nested transactions separately. After commit hooks are never called.
+//
+//	func TestRepository(t *testing.T) {
+//		ctx, wrapper := sqltxtest.RollbackTx(t, db)
+//		repo := NewRepository(wrapper)
+//		...
+//	}
+func RollbackTx(t testing.TB, db *sql.DB, opts ...sqltx.WrapperOption) (context.Context, sqltx.Wrapper) {
+	t.Helper()
+	wrapper := sqltx.NewDefaultWrapper(db, testLogger{t: t}, opts...)
+
+	ctxCh := make(chan context.Context)
+	release := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		result <- wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+			ctxCh <- ctx
+			<-release
+			return errRollback
+		})
+	}()
+
+	select {
+	case ctx := <-ctxCh:
+		t.Cleanup(func() {
+			close(release)
+			if err := <-result; !errors.Is(err, errRollback) {
+				t.Errorf("cannot roll back test transaction: %s", err)
+			}
+		})
+		return ctx, wrapper
+	case err := <-result:
+		t.Fatalf("cannot begin test transaction: %s", err)
+		return nil, nil
+	}
+}
+
+type testLogger struct {
+	t testing.TB
+}
+
+func (l testLogger) Warn(msg string, args ...any) {
+	l.t.Helper()
+	l.t.Log(append([]any{msg}, args...)...)
+}
@@ -0,0 +1,54 @@
+package sqltxtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/sqltxtest"
+)
+
+func TestRollbackTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO test").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT sqltx_2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	t.Run("test", func(t *testing.T) {
+		ctx, wrapper := sqltxtest.RollbackTx(t, db, sqltx.WithSavepoints(sqltx.MySQL))
+		sqltxtest.AssertDepth(t, ctx, 1)
+
+		err := wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := wrapper.Connection(ctx).ExecContext(ctx, "INSERT INTO test VALUES (1)")
+			return err
+		})
+		require.NoError(t, err)
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAssertDepth(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	require.True(t, sqltxtest.AssertDepth(tb, context.Background(), 0))
+	require.False(t, tb.failed)
+	require.False(t, sqltxtest.AssertDepth(tb, context.Background(), 1))
+	require.True(t, tb.failed)
+}
+
+type fakeTB struct {
+	testing.TB
+	failed bool
+}
+
+func (tb *fakeTB) Errorf(string, ...any) {
+	tb.failed = true
+}
@@ -0,0 +1,228 @@
+package sqltxtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ErrQueryNotSupported is returned for queries run with the fake wrapper unless they are handled by Handler
+var ErrQueryNotSupported = errors.New("sqltxtest: queries are not supported by the fake wrapper")
+
+// Query is the query run with the fake wrapper
+type Query struct {
+	// Session is the number of the database connection starting from 1
+	Session int
+	SQL     string
+	Args    []any
+	// InTx reports whether the query is run within a transaction
+	InTx bool
+}
+
+// Rows is the result of the query returned by Handler, Values are the rows of the columns
+type Rows struct {
+	Columns []string
+	Values  [][]any
+}
+
+// Handler returns the result of the query run with the fake wrapper, nil rows mean an empty result
+type Handler func(q Query) (*Rows, error)
+
+// Tx is the transaction recorded by the fake wrapper
+type Tx struct {
+	Options sql.TxOptions
+	// Statements are savepoint statements executed within the transaction
+	Statements []string
+	Committed  bool
+	RolledBack bool
+}
+
+// recorder keeps transactions and simulated failures
+type recorder struct {
+	mu          sync.Mutex
+	sessions    int
+	handler     Handler
+	txs         []*Tx
+	beginErr    error
+	commitErr   error
+	rollbackErr error
+}
+
+func (r *recorder) begin(opts driver.TxOptions) (*Tx, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.beginErr; err != nil {
+		r.beginErr = nil
+		return nil, err
+	}
+	tx := &Tx{Options: sql.TxOptions{Isolation: sql.IsolationLevel(opts.Isolation), ReadOnly: opts.ReadOnly}}
+	r.txs = append(r.txs, tx)
+	return tx, nil
+}
+
+// finish marks the transaction as committed or rolled back unless the failure is simulated
+func (r *recorder) finish(tx *Tx, commit bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if commit {
+		if err := r.commitErr; err != nil {
+			r.commitErr = nil
+			tx.RolledBack = true
+			return err
+		}
+		tx.Committed = true
+		return nil
+	}
+	tx.RolledBack = true
+	if err := r.rollbackErr; err != nil {
+		r.rollbackErr = nil
+		return err
+	}
+	return nil
+}
+
+// exec records savepoint statements and passes other queries to the handler
+func (r *recorder) exec(c *conn, query string, args []driver.NamedValue) (*Rows, error) {
+	r.mu.Lock()
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	isSavepoint := strings.HasPrefix(upper, "SAVEPOINT") ||
+		strings.HasPrefix(upper, "RELEASE SAVEPOINT") ||
+		strings.HasPrefix(upper, "ROLLBACK TO SAVEPOINT")
+	if c.tx != nil && isSavepoint {
+		c.tx.Statements = append(c.tx.Statements, query)
+		r.mu.Unlock()
+		return nil, nil
+	}
+	handler := r.handler
+	r.mu.Unlock()
+
+	if handler == nil {
+		return nil, ErrQueryNotSupported
+	}
+	q := Query{Session: c.session, SQL: query, InTx: c.tx != nil}
+	for _, arg := range args {
+		q.Args = append(q.Args, arg.Value)
+	}
+	return handler(q)
+}
+
+func (r *recorder) transactions() []Tx {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	txs := make([]Tx, len(r.txs))
+	for i, tx := range r.txs {
+		txs[i] = *tx
+		txs[i].Statements = append([]string(nil), tx.Statements...)
+	}
+	return txs
+}
+
+// connector opens connections recording transactions
+type connector struct {
+	rec *recorder
+}
+
+func (c *connector) Connect(context.Context) (driver.Conn, error) {
+	c.rec.mu.Lock()
+	defer c.rec.mu.Unlock()
+	c.rec.sessions++
+	return &conn{rec: c.rec, session: c.rec.sessions}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("sqltxtest: use connector")
+}
+
+type conn struct {
+	rec     *recorder
+	session int
+	tx      *Tx
+}
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, ErrQueryNotSupported
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	t, err := c.rec.begin(opts)
+	if err != nil {
+		return nil, err
+	}
+	c.tx = t
+	return &tx{conn: c, tx: t}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, err := c.rec.exec(c, query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.rec.exec(c, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &Rows{}
+	}
+	return &rows{result: result}, nil
+}
+
+type rows struct {
+	result *Rows
+	next   int
+}
+
+func (r *rows) Columns() []string {
+	return r.result.Columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Values) {
+		return io.EOF
+	}
+	for i, v := range r.result.Values[r.next] {
+		dest[i] = v
+	}
+	r.next++
+	return nil
+}
+
+type tx struct {
+	conn *conn
+	tx   *Tx
+}
+
+func (t *tx) Commit() error {
+	t.conn.tx = nil
+	return t.conn.rec.finish(t.tx, true)
+}
+
+func (t *tx) Rollback() error {
+	t.conn.tx = nil
+	return t.conn.rec.finish(t.tx, false)
+}
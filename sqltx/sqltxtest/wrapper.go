@@ -0,0 +1,143 @@
+// Package sqltxtest provides utilities for testing code which uses sqltx.Wrapper
+package sqltxtest
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/velmie/x/sqltx"
+)
+
+// Call is the recorded WithTransaction call
+type Call struct {
+	// Depth is the nesting level of the call, 1 means the outermost transaction
+	Depth   int
+	Options sqltx.Options
+	Err     error
+}
+
+// Wrapper is the fake sqltx.Wrapper which records transactions without a database.
+// It runs sqltx.DefaultWrapper on top of the in-memory driver, so nesting, savepoints and hooks work the same way.
+// Queries fail with ErrQueryNotSupported unless Handle is called, stub repositories in order to test the code
+// using the fake
+type Wrapper struct {
+	*sqltx.DefaultWrapper
+	db  *sql.DB
+	rec *recorder
+
+	mu       sync.Mutex
+	calls    []Call
+	maxDepth int
+}
+
+// NewWrapper creates the fake wrapper, the options are passed to sqltx.DefaultWrapper
+func NewWrapper(opts ...sqltx.WrapperOption) *Wrapper {
+	rec := &recorder{}
+	db := sql.OpenDB(&connector{rec: rec})
+	return &Wrapper{
+		DefaultWrapper: sqltx.NewDefaultWrapper(db, noopLogger{}, opts...),
+		db:             db,
+		rec:            rec,
+	}
+}
+
+// WithTransaction records the call and runs the function within the fake transaction
+func (w *Wrapper) WithTransaction(ctx context.Context, f func(ctx context.Context) error, opts ...sqltx.Option) error {
+	depth := sqltx.Depth(ctx) + 1
+	w.mu.Lock()
+	if depth > w.maxDepth {
+		w.maxDepth = depth
+	}
+	w.mu.Unlock()
+
+	err := w.DefaultWrapper.WithTransaction(ctx, f, opts...)
+
+	w.mu.Lock()
+	w.calls = append(w.calls, Call{Depth: depth, Options: sqltx.ApplyOptions(opts...), Err: err})
+	w.mu.Unlock()
+	return err
+}
+
+// Handle sets the handler of queries run with the wrapper, e.g. in order to stub locking functions
+func (w *Wrapper) Handle(h Handler) {
+	w.rec.mu.Lock()
+	defer w.rec.mu.Unlock()
+	w.rec.handler = h
+}
+
+// DB returns the fake database of the wrapper
+func (w *Wrapper) DB() *sql.DB {
+	return w.db
+}
+
+// FailBegin makes the next transaction fail to begin with the error
+func (w *Wrapper) FailBegin(err error) {
+	w.rec.mu.Lock()
+	defer w.rec.mu.Unlock()
+	w.rec.beginErr = err
+}
+
+// FailCommit makes the next commit fail with the error
+func (w *Wrapper) FailCommit(err error) {
+	w.rec.mu.Lock()
+	defer w.rec.mu.Unlock()
+	w.rec.commitErr = err
+}
+
+// FailRollback makes the next rollback fail with the error
+func (w *Wrapper) FailRollback(err error) {
+	w.rec.mu.Lock()
+	defer w.rec.mu.Unlock()
+	w.rec.rollbackErr = err
+}
+
+// Transactions returns the transactions begun by the wrapper in order
+func (w *Wrapper) Transactions() []Tx {
+	return w.rec.transactions()
+}
+
+// Calls returns WithTransaction calls in order of completion, i.e. nested calls go first
+func (w *Wrapper) Calls() []Call {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Call(nil), w.calls...)
+}
+
+// MaxDepth returns the maximum nesting level of WithTransaction calls
+func (w *Wrapper) MaxDepth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.maxDepth
+}
+
+// Commits returns the number of committed transactions
+func (w *Wrapper) Commits() int {
+	n := 0
+	for _, tx := range w.Transactions() {
+		if tx.Committed {
+			n++
+		}
+	}
+	return n
+}
+
+// Rollbacks returns the number of rolled back transactions, including failed commits
+func (w *Wrapper) Rollbacks() int {
+	n := 0
+	for _, tx := range w.Transactions() {
+		if tx.RolledBack {
+			n++
+		}
+	}
+	return n
+}
+
+// Close closes the fake database
+func (w *Wrapper) Close() error {
+	return w.db.Close()
+}
+
+type noopLogger struct{}
+
+func (noopLogger) Warn(string, ...any) {}
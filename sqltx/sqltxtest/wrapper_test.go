@@ -0,0 +1,146 @@
+package sqltxtest_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/sqltx"
+	"github.com/velmie/x/sqltx/sqltxtest"
+)
+
+func TestWrapper_RecordsTransactions(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper(sqltx.WithSavepoints(sqltx.MySQL))
+	defer wrapper.Close()
+
+	var hookCalled bool
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		sqltxtest.AssertDepth(t, ctx, 1)
+		require.NoError(t, sqltx.AfterCommit(ctx, func(context.Context) error {
+			hookCalled = true
+			return nil
+		}))
+		return wrapper.WithTransaction(ctx, func(ctx context.Context) error {
+			sqltxtest.AssertDepth(t, ctx, 2)
+			return nil
+		})
+	}, sqltx.WithIsolationLevel(sql.LevelSerializable))
+
+	require.NoError(t, err)
+	require.True(t, hookCalled)
+	require.Equal(t, 2, wrapper.MaxDepth())
+	require.Equal(t, 1, wrapper.Commits())
+	require.Equal(t, 0, wrapper.Rollbacks())
+
+	txs := wrapper.Transactions()
+	require.Len(t, txs, 1)
+	require.Equal(t, sql.LevelSerializable, txs[0].Options.Isolation)
+	require.Equal(t, []string{"SAVEPOINT sqltx_2", "RELEASE SAVEPOINT sqltx_2"}, txs[0].Statements)
+
+	calls := wrapper.Calls()
+	require.Len(t, calls, 2)
+	require.Equal(t, 2, calls[0].Depth)
+	require.Equal(t, 1, calls[1].Depth)
+	require.Equal(t, sql.LevelSerializable, calls[1].Options.TxOptions.Isolation)
+}
+
+func TestWrapper_RollbackOnError(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper()
+	defer wrapper.Close()
+
+	errTest := errors.New("test")
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return errTest
+	}, sqltx.ReadOnly())
+
+	require.ErrorIs(t, err, errTest)
+	require.Equal(t, 0, wrapper.Commits())
+	require.Equal(t, 1, wrapper.Rollbacks())
+	require.True(t, wrapper.Transactions()[0].Options.ReadOnly)
+	require.ErrorIs(t, wrapper.Calls()[0].Err, errTest)
+}
+
+func TestWrapper_FailBegin(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper()
+	defer wrapper.Close()
+
+	errBegin := errors.New("begin")
+	wrapper.FailBegin(errBegin)
+
+	called := false
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+
+	require.ErrorIs(t, err, errBegin)
+	require.False(t, called)
+	require.Empty(t, wrapper.Transactions())
+
+	require.NoError(t, wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return nil
+	}))
+	require.Equal(t, 1, wrapper.Commits())
+}
+
+func TestWrapper_FailCommit(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper()
+	defer wrapper.Close()
+
+	errCommit := errors.New("commit")
+	wrapper.FailCommit(errCommit)
+
+	var rollbackHookCalled bool
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return sqltx.AfterRollback(ctx, func(context.Context) error {
+			rollbackHookCalled = true
+			return nil
+		})
+	})
+
+	require.ErrorIs(t, err, errCommit)
+	require.True(t, rollbackHookCalled)
+	require.Equal(t, 0, wrapper.Commits())
+	require.Equal(t, 1, wrapper.Rollbacks())
+}
+
+func TestWrapper_QueryNotSupported(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper()
+	defer wrapper.Close()
+
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		_, err := wrapper.Connection(ctx).ExecContext(ctx, "INSERT INTO test VALUES (1)")
+		return err
+	})
+
+	require.ErrorIs(t, err, sqltxtest.ErrQueryNotSupported)
+	require.Equal(t, 1, wrapper.Rollbacks())
+}
+
+func TestWrapper_Handle(t *testing.T) {
+	wrapper := sqltxtest.NewWrapper()
+	defer wrapper.Close()
+
+	var queries []sqltxtest.Query
+	wrapper.Handle(func(q sqltxtest.Query) (*sqltxtest.Rows, error) {
+		queries = append(queries, q)
+		return &sqltxtest.Rows{Columns: []string{"count"}, Values: [][]any{{int64(42)}}}, nil
+	})
+
+	var count int64
+	err := wrapper.WithTransaction(context.Background(), func(ctx context.Context) error {
+		return wrapper.Connection(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM test WHERE id > ?", 1).Scan(&count)
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+
+	_, err = wrapper.Connection(context.Background()).ExecContext(context.Background(), "DELETE FROM test")
+	require.NoError(t, err)
+
+	require.Len(t, queries, 2)
+	require.Equal(t, sqltxtest.Query{Session: 1, SQL: "SELECT COUNT(*) FROM test WHERE id > ?", Args: []any{int64(1)}, InTx: true}, queries[0])
+	require.Equal(t, sqltxtest.Query{Session: 1, SQL: "DELETE FROM test"}, queries[1])
+}
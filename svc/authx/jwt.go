@@ -169,11 +169,10 @@ func fallbackSource(a, b namedKeySource, log Logger) authentication.KeySource {
 		}
 		if log != nil {
 			log.Warn(
-				fmt.Sprintf("failed to fetch key using the first key source '%s', fallback to the second key source '%s': %s",
-					a.name,
-					b.name,
-					err,
-				),
+				"failed to fetch key using the first key source, fallback to the second key source",
+				"source", a.name,
+				"fallbackSource", b.name,
+				"error", err.Error(),
 			)
 		}
 		return b.source.FetchPublicKey(ctx, kid)
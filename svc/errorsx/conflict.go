@@ -0,0 +1,56 @@
+package errorsx
+
+import (
+	"net/http"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// ErrCodeConflict is the error code used when the request conflicts with the current state of the resource.
+const ErrCodeConflict = "CONFLICT"
+
+// ConflictError represents an error due to a conflicting resource state, e.g. optimistic lock failure.
+type ConflictError struct {
+	ResourceName string // The name of the resource, e.g. "order".
+	ResourceID   string // The unique identifier of the resource.
+	Version      string // The current version of the resource, if known.
+	Cause        error  // The underlying error which caused this error.
+}
+
+// Error satisfies the error interface for ConflictError.
+func (c *ConflictError) Error() string {
+	message := "conflict"
+	if c.ResourceName != "" {
+		message = message + " on " + c.ResourceName
+	}
+	if c.ResourceID != "" {
+		message = message + " " + c.ResourceID
+	}
+	if c.Version != "" {
+		message = message + " (current version " + c.Version + ")"
+	}
+	if c.Cause != nil {
+		message = message + ": " + c.Cause.Error()
+	}
+	return message
+}
+
+func (c *ConflictError) HTTPError() *response.HTTPError {
+	meta := resourceMeta(c.ResourceName, c.ResourceID)
+	if c.Version != "" {
+		if meta == nil {
+			meta = make(map[string]any, 1)
+		}
+		meta["version"] = c.Version
+	}
+	return &response.HTTPError{
+		Code:       ErrCodeConflict,
+		Meta:       meta,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusConflict,
+	}
+}
+
+func (c *ConflictError) Unwrap() error {
+	return c.Cause
+}
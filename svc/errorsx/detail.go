@@ -0,0 +1,208 @@
+package errorsx
+
+import (
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const maxStackDepth = 32
+
+// DetailOption configures DetailedError
+type DetailOption func(e *DetailedError)
+
+// WithCode sets the stable machine readable code of the error, e.g. "ORDER_PAYMENT_DECLINED"
+func WithCode(code string) DetailOption {
+	return func(e *DetailedError) {
+		e.code = code
+	}
+}
+
+// WithAttrs adds key/value attributes to the error.
+// Arguments are handled the same way as by slog.Logger.Info: either slog.Attr or key, value pairs
+func WithAttrs(args ...any) DetailOption {
+	return func(e *DetailedError) {
+		e.attrs = append(e.attrs, argsToAttrs(args)...)
+	}
+}
+
+// DetailedError wraps an error with the stack trace captured at the wrapping point,
+// a machine readable code and key/value attributes.
+type DetailedError struct {
+	cause error
+	code  string
+	attrs []slog.Attr
+	stack []uintptr
+}
+
+// Wrap wraps the error with details, nil is returned for nil error.
+// The stack trace is captured only if the error chain does not contain a stack trace yet,
+// so the origin of the error is preserved when it is wrapped multiple times
+func Wrap(err error, opts ...DetailOption) error {
+	if err == nil {
+		return nil
+	}
+	e := &DetailedError{cause: err}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if !hasStack(err) {
+		e.stack = callers()
+	}
+	return e
+}
+
+// New creates a new error with the given message and details, the stack trace is always captured
+func New(message string, opts ...DetailOption) error {
+	e := &DetailedError{cause: messageError(message)}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.stack = callers()
+	return e
+}
+
+// Error returns the message of the wrapped error, details are available via other methods
+func (e *DetailedError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *DetailedError) Unwrap() error {
+	return e.cause
+}
+
+// ErrorCode returns the machine readable code of the error, it could be empty
+func (e *DetailedError) ErrorCode() string {
+	return e.code
+}
+
+// ErrorAttrs returns attributes of the error, it does not include attributes of the wrapped errors
+func (e *DetailedError) ErrorAttrs() []slog.Attr {
+	return e.attrs
+}
+
+// Frames returns the captured stack frames, nil is returned if the stack was not captured
+func (e *DetailedError) Frames() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(e.stack)
+	result := make([]runtime.Frame, 0, len(e.stack))
+	for {
+		frame, more := frames.Next()
+		result = append(result, frame)
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+// StackTrace returns the captured stack trace formatted similarly to runtime/debug.Stack
+func (e *DetailedError) StackTrace() string {
+	var sb strings.Builder
+	for _, frame := range e.Frames() {
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// LogValue implements slog.LogValuer, the error is logged as a group containing
+// the message, the code, attributes of the whole chain and the stack trace
+func (e *DetailedError) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 4)
+	attrs = append(attrs, slog.String("msg", e.Error()))
+	if code := Code(e); code != "" {
+		attrs = append(attrs, slog.String("code", code))
+	}
+	attrs = append(attrs, Attrs(e)...)
+	if stack := StackTrace(e); stack != "" {
+		attrs = append(attrs, slog.String("stack", stack))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Code returns the first non-empty code found in the error chain
+func Code(err error) (code string) {
+	UnwrapF(err, func(target error) bool {
+		if e, ok := target.(interface{ ErrorCode() string }); ok {
+			code = e.ErrorCode()
+		}
+		return code != ""
+	})
+	return code
+}
+
+// Attrs returns attributes of all errors of the chain, attributes of outer errors come first
+func Attrs(err error) (attrs []slog.Attr) {
+	UnwrapF(err, func(target error) bool {
+		if e, ok := target.(interface{ ErrorAttrs() []slog.Attr }); ok {
+			attrs = append(attrs, e.ErrorAttrs()...)
+		}
+		return false
+	})
+	return attrs
+}
+
+// StackTrace returns the stack trace captured closest to the origin of the error,
+// an empty string is returned if the chain does not contain a stack trace
+func StackTrace(err error) (stack string) {
+	UnwrapF(err, func(target error) bool {
+		if e, ok := target.(interface{ StackTrace() string }); ok {
+			if s := e.StackTrace(); s != "" {
+				stack = s
+			}
+		}
+		return false
+	})
+	return stack
+}
+
+func hasStack(err error) bool {
+	return UnwrapF(err, func(target error) bool {
+		e, ok := target.(*DetailedError)
+		return ok && len(e.stack) > 0
+	})
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	// skip runtime.Callers, callers and the exported constructor
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(args))
+	for len(args) > 0 {
+		switch x := args[0].(type) {
+		case slog.Attr:
+			attrs = append(attrs, x)
+			args = args[1:]
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, slog.String("!BADKEY", x))
+				args = nil
+			} else {
+				attrs = append(attrs, slog.Any(x, args[1]))
+				args = args[2:]
+			}
+		default:
+			attrs = append(attrs, slog.Any("!BADKEY", x))
+			args = args[1:]
+		}
+	}
+	return attrs
+}
+
+type messageError string
+
+func (e messageError) Error() string {
+	return string(e)
+}
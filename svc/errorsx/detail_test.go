@@ -0,0 +1,75 @@
+package errorsx_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/velmie/x/svc/errorsx"
+)
+
+func findOrder() error {
+	return errorsx.Wrap(sql.ErrNoRows, errorsx.WithCode("ORDER_NOT_FOUND"), errorsx.WithAttrs("orderId", 42))
+}
+
+func TestWrap(t *testing.T) {
+	if errorsx.Wrap(nil) != nil {
+		t.Fatal("expected nil for nil error")
+	}
+
+	err := fmt.Errorf("pay: %w", errorsx.Wrap(findOrder(), errorsx.WithAttrs(slog.String("userId", "7"))))
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Error("expected the cause to be unwrapped")
+	}
+	if err.Error() != "pay: "+sql.ErrNoRows.Error() {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	var detailed *errorsx.DetailedError
+	if !errors.As(err, &detailed) {
+		t.Fatal("expected DetailedError to be found by errors.As")
+	}
+
+	if code := errorsx.Code(err); code != "ORDER_NOT_FOUND" {
+		t.Errorf("expected code ORDER_NOT_FOUND, got %q", code)
+	}
+
+	attrs := errorsx.Attrs(err)
+	if len(attrs) != 2 || attrs[0].Key != "userId" || attrs[1].Key != "orderId" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+
+	stack := errorsx.StackTrace(err)
+	if !strings.Contains(stack, "errorsx_test.findOrder") {
+		t.Errorf("expected the stack trace to point to the origin, got:\n%s", stack)
+	}
+	if strings.Contains(stack, "errorsx.Wrap") {
+		t.Errorf("expected Wrap to be skipped, got:\n%s", stack)
+	}
+}
+
+func TestDetailedError_LogValue(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	logger.Error("request failed", "error", errorsx.New("declined", errorsx.WithCode("DECLINED"), errorsx.WithAttrs("amount", 10)))
+
+	var record struct {
+		Error map[string]any `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Error["msg"] != "declined" || record.Error["code"] != "DECLINED" || record.Error["amount"] != float64(10) {
+		t.Errorf("unexpected log record %s", buf.String())
+	}
+	if stack, _ := record.Error["stack"].(string); !strings.Contains(stack, "TestDetailedError_LogValue") {
+		t.Errorf("expected stack trace in the log record %s", buf.String())
+	}
+}
@@ -0,0 +1,153 @@
+package errorsx_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/velmie/x/svc/errorsx"
+	"github.com/velmie/x/svc/http/response"
+)
+
+func TestDomainErrors_HTTPError(t *testing.T) {
+	expiredAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedCode   string
+		expectedStatus int
+		expectedMeta   map[string]any
+	}{
+		{
+			name:           "NotFoundError",
+			err:            &errorsx.NotFoundError{ResourceName: "order", ResourceID: "42", Cause: sql.ErrNoRows},
+			expectedCode:   response.ErrCodeNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedMeta:   map[string]any{"resource": "order", "id": "42"},
+		},
+		{
+			name:           "ConflictError",
+			err:            &errorsx.ConflictError{ResourceName: "order", ResourceID: "42", Version: "7"},
+			expectedCode:   errorsx.ErrCodeConflict,
+			expectedStatus: http.StatusConflict,
+			expectedMeta:   map[string]any{"resource": "order", "id": "42", "version": "7"},
+		},
+		{
+			name:           "RateLimitError",
+			err:            &errorsx.RateLimitError{Limit: 10, Window: time.Minute, RetryAfter: 1500 * time.Millisecond},
+			expectedCode:   response.ErrCodeRateLimitExceeded,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedMeta:   map[string]any{"limit": 10, "window": 60, "retryAfter": 2},
+		},
+		{
+			name:           "ExpiredError",
+			err:            &errorsx.ExpiredError{ResourceName: "invitation", ExpiredAt: expiredAt},
+			expectedCode:   response.ErrCodeExpired,
+			expectedStatus: http.StatusGone,
+			expectedMeta:   map[string]any{"resource": "invitation", "expiredAt": "2024-05-01T10:00:00Z"},
+		},
+		{
+			name:           "ExhaustedError",
+			err:            &errorsx.ExhaustedError{ResourceName: "attempts", Limit: 3},
+			expectedCode:   response.ErrCodeExhausted,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedMeta:   map[string]any{"resource": "attempts", "limit": 3},
+		},
+	}
+
+	mapper := errorsx.NewMapper(errorsx.DefaultRules())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpErr := mapper.Map(fmt.Errorf("wrapped: %w", tt.err))
+			if httpErr.Code != tt.expectedCode || httpErr.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected %s/%d, got %s/%d", tt.expectedCode, tt.expectedStatus, httpErr.Code, httpErr.StatusCode)
+			}
+			if len(httpErr.Meta) != len(tt.expectedMeta) {
+				t.Fatalf("expected meta %v, got %v", tt.expectedMeta, httpErr.Meta)
+			}
+			for k, v := range tt.expectedMeta {
+				if httpErr.Meta[k] != v {
+					t.Errorf("expected meta %q to be %v, got %v", k, v, httpErr.Meta[k])
+				}
+			}
+		})
+	}
+}
+
+func TestNotFoundError_Unwrap(t *testing.T) {
+	err := &errorsx.NotFoundError{ResourceName: "order", ResourceID: "42", Cause: sql.ErrNoRows}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Error("expected the cause to be unwrapped")
+	}
+	if expected := "order not found: 42: " + sql.ErrNoRows.Error(); err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	verr := &errorsx.ValidationError{}
+	if verr.Err() != nil {
+		t.Fatal("expected nil error when there are no violations")
+	}
+
+	err := verr.
+		Add("/email", "is invalid", nil).
+		Add("/age", "is too small", map[string]any{"rule": "min", "min": 18}).
+		Err()
+
+	httpErrs := errorsx.NewMapper(errorsx.DefaultRules()).MapAll(fmt.Errorf("wrapped: %w", err))
+	if len(httpErrs) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(httpErrs))
+	}
+	for i, source := range []string{"/email", "/age"} {
+		if httpErrs[i].Source != source || httpErrs[i].Target != response.TargetField {
+			t.Errorf("unexpected error %d: %+v", i, httpErrs[i])
+		}
+		if httpErrs[i].Code != response.ErrCodeInvalidRequestParameter || httpErrs[i].StatusCode != http.StatusBadRequest {
+			t.Errorf("unexpected error %d: %+v", i, httpErrs[i])
+		}
+	}
+	if expected := "validation failed: /email is invalid; /age is too small"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+type errorsWriter struct {
+	errs []*response.HTTPError
+}
+
+func (e *errorsWriter) Write(w http.ResponseWriter, _ *http.Request, errs ...*response.HTTPError) error {
+	e.errs = errs
+	w.WriteHeader(errs[0].StatusCode)
+	return nil
+}
+
+func TestMapper_Render(t *testing.T) {
+	mapper := errorsx.NewMapper(errorsx.DefaultRules())
+	err := fmt.Errorf("too many attempts: %w", &errorsx.RateLimitError{Limit: 5, RetryAfter: 30 * time.Second})
+
+	rec := httptest.NewRecorder()
+	ew := &errorsWriter{}
+	if err := mapper.Render(rec, httptest.NewRequest(http.MethodGet, "/", nil), ew, err); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if actual := rec.Header().Get("Retry-After"); actual != "30" {
+		t.Errorf("expected Retry-After 30, got %q", actual)
+	}
+	if actual := rec.Header().Get("X-RateLimit-Limit"); actual != "5" {
+		t.Errorf("expected X-RateLimit-Limit 5, got %q", actual)
+	}
+	if len(ew.errs) != 1 || ew.errs[0].Code != response.ErrCodeRateLimitExceeded {
+		t.Errorf("unexpected errors: %v", ew.errs)
+	}
+}
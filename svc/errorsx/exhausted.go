@@ -0,0 +1,50 @@
+package errorsx
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// ExhaustedError represents an error due to an exhausted resource limit, e.g. attempts or a quota.
+type ExhaustedError struct {
+	ResourceName string // The name of the exhausted resource, e.g. "verification attempts".
+	Limit        int    // The limit which has been exhausted, 0 if not specified.
+	Cause        error  // The underlying error which caused this error.
+}
+
+// Error satisfies the error interface for ExhaustedError.
+func (e *ExhaustedError) Error() string {
+	message := "resource is exhausted"
+	if e.ResourceName != "" {
+		message = e.ResourceName + " is exhausted"
+	}
+	if e.Limit > 0 {
+		message = message + fmt.Sprintf(" (limit %d)", e.Limit)
+	}
+	if e.Cause != nil {
+		message = message + ": " + e.Cause.Error()
+	}
+	return message
+}
+
+func (e *ExhaustedError) HTTPError() *response.HTTPError {
+	meta := resourceMeta(e.ResourceName, "")
+	if e.Limit > 0 {
+		if meta == nil {
+			meta = make(map[string]any, 1)
+		}
+		meta["limit"] = e.Limit
+	}
+	return &response.HTTPError{
+		Code:       response.ErrCodeExhausted,
+		Meta:       meta,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusUnprocessableEntity,
+	}
+}
+
+func (e *ExhaustedError) Unwrap() error {
+	return e.Cause
+}
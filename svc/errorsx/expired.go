@@ -0,0 +1,51 @@
+package errorsx
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// ExpiredError represents an error due to an expired resource, e.g. a confirmation code or an invitation link.
+type ExpiredError struct {
+	ResourceName string    // The name of the resource which has expired.
+	ResourceID   string    // The unique identifier of the resource.
+	ExpiredAt    time.Time // The moment the resource has expired at, if known.
+	Cause        error     // The underlying error which caused this error.
+}
+
+// Error satisfies the error interface for ExpiredError.
+func (e *ExpiredError) Error() string {
+	message := "resource has expired"
+	if e.ResourceName != "" {
+		message = e.ResourceName + " has expired"
+	}
+	if !e.ExpiredAt.IsZero() {
+		message = message + " at " + e.ExpiredAt.Format(time.RFC3339)
+	}
+	if e.Cause != nil {
+		message = message + ": " + e.Cause.Error()
+	}
+	return message
+}
+
+func (e *ExpiredError) HTTPError() *response.HTTPError {
+	meta := resourceMeta(e.ResourceName, e.ResourceID)
+	if !e.ExpiredAt.IsZero() {
+		if meta == nil {
+			meta = make(map[string]any, 1)
+		}
+		meta["expiredAt"] = e.ExpiredAt.UTC().Format(time.RFC3339)
+	}
+	return &response.HTTPError{
+		Code:       response.ErrCodeExpired,
+		Meta:       meta,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusGone,
+	}
+}
+
+func (e *ExpiredError) Unwrap() error {
+	return e.Cause
+}
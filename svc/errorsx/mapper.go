@@ -0,0 +1,206 @@
+package errorsx
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"reflect"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// StatusClientClosedRequest is a non-standard status code used when the client has closed the connection
+// before the response is ready
+const StatusClientClosedRequest = 499
+
+// HTTPErrorProvider is implemented by errors which know their HTTP representation
+type HTTPErrorProvider interface {
+	HTTPError() *response.HTTPError
+}
+
+// HTTPErrorsProvider is implemented by errors which are represented by multiple HTTP errors,
+// e.g. validation errors
+type HTTPErrorsProvider interface {
+	HTTPErrors() []*response.HTTPError
+}
+
+// Rule maps the error to response.HTTPError.
+// The second returned value reports whether the rule is applicable to the error
+type Rule interface {
+	Map(err error) (*response.HTTPError, bool)
+}
+
+// RuleFunc is an adapter to allow the use of ordinary functions as Rule
+type RuleFunc func(err error) (*response.HTTPError, bool)
+
+// Map calls f(err)
+func (f RuleFunc) Map(err error) (*response.HTTPError, bool) {
+	return f(err)
+}
+
+// Sentinel creates a rule which maps the target error to a copy of the given HTTP error.
+// The error matches if it is equal to the target or its Is(error) bool method reports so.
+// Unlike errors.Is the wrapped errors are not checked since Mapper walks the chain itself
+func Sentinel(target error, httpErr *response.HTTPError) Rule {
+	isComparable := reflect.TypeOf(target).Comparable()
+	return RuleFunc(func(err error) (*response.HTTPError, bool) {
+		if isComparable && err == target {
+			return copyHTTPError(httpErr), true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return copyHTTPError(httpErr), true
+		}
+		return nil, false
+	})
+}
+
+// Type creates a rule which maps errors of the type T using the given function
+func Type[T error](f func(err T) *response.HTTPError) Rule {
+	return RuleFunc(func(err error) (*response.HTTPError, bool) {
+		if target, ok := err.(T); ok {
+			return f(target), true
+		}
+		return nil, false
+	})
+}
+
+// Provider creates a rule which maps errors implementing HTTPErrorProvider
+func Provider() Rule {
+	return RuleFunc(func(err error) (*response.HTTPError, bool) {
+		if p, ok := err.(HTTPErrorProvider); ok {
+			if httpErr := p.HTTPError(); httpErr != nil {
+				return httpErr, true
+			}
+		}
+		return nil, false
+	})
+}
+
+// ContextRules maps context.DeadlineExceeded and context.Canceled errors
+func ContextRules() []Rule {
+	return []Rule{
+		Sentinel(context.DeadlineExceeded, &response.HTTPError{
+			Code:       response.ErrCodeInternalServerError,
+			Target:     response.TargetCommon,
+			StatusCode: http.StatusGatewayTimeout,
+		}),
+		Sentinel(context.Canceled, &response.HTTPError{
+			Code:       response.ErrCodeInternalServerError,
+			Target:     response.TargetCommon,
+			StatusCode: StatusClientClosedRequest,
+		}),
+	}
+}
+
+// SQLRules maps sql.ErrNoRows to the not found error
+func SQLRules() []Rule {
+	return []Rule{
+		Sentinel(sql.ErrNoRows, &response.HTTPError{
+			Code:       response.ErrCodeNotFound,
+			Target:     response.TargetCommon,
+			StatusCode: http.StatusNotFound,
+		}),
+	}
+}
+
+// DefaultRules combines Provider, ContextRules and SQLRules
+func DefaultRules() []Rule {
+	rules := []Rule{Provider()}
+	rules = append(rules, ContextRules()...)
+	return append(rules, SQLRules()...)
+}
+
+// MapperOption configures Mapper
+type MapperOption func(m *Mapper)
+
+// WithFallback sets the function which maps errors not matched by any rule.
+// By default, such errors are mapped to the internal server error without exposing the error message
+func WithFallback(f func(err error) *response.HTTPError) MapperOption {
+	return func(m *Mapper) {
+		m.fallback = f
+	}
+}
+
+// Mapper maps errors to response.HTTPError using the registered rules.
+//
+// The error chain is walked from the outermost error, every rule is tried in the registration order
+// against each error of the chain, so the outermost matching error wins.
+// Errors implementing HTTPErrorsProvider are expanded by MapAll
+type Mapper struct {
+	rules    []Rule
+	fallback func(err error) *response.HTTPError
+}
+
+// NewMapper creates a new Mapper with the given rules
+func NewMapper(rules []Rule, opts ...MapperOption) *Mapper {
+	m := &Mapper{
+		rules:    rules,
+		fallback: internalServerError,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// With returns a new Mapper which tries the given rules before the rules of m
+func (m *Mapper) With(rules ...Rule) *Mapper {
+	combined := make([]Rule, 0, len(rules)+len(m.rules))
+	combined = append(combined, rules...)
+	combined = append(combined, m.rules...)
+	return &Mapper{rules: combined, fallback: m.fallback}
+}
+
+// Map maps the error to response.HTTPError, nil is returned for nil error
+func (m *Mapper) Map(err error) *response.HTTPError {
+	if err == nil {
+		return nil
+	}
+	var result *response.HTTPError
+	UnwrapF(err, func(target error) bool {
+		for _, r := range m.rules {
+			if httpErr, ok := r.Map(target); ok {
+				result = httpErr
+				return true
+			}
+		}
+		return false
+	})
+	if result == nil {
+		result = m.fallback(err)
+	}
+	return result
+}
+
+// MapAll maps the error to the list of response.HTTPError.
+// It expands the first error of the chain implementing HTTPErrorsProvider, otherwise it is the same as Map
+func (m *Mapper) MapAll(err error) []*response.HTTPError {
+	if err == nil {
+		return nil
+	}
+	if p := As[HTTPErrorsProvider](err); p != nil {
+		if httpErrs := p.HTTPErrors(); len(httpErrs) > 0 {
+			return httpErrs
+		}
+	}
+	return []*response.HTTPError{m.Map(err)}
+}
+
+func internalServerError(error) *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeInternalServerError,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusInternalServerError,
+	}
+}
+
+func copyHTTPError(e *response.HTTPError) *response.HTTPError {
+	c := *e
+	if e.Meta != nil {
+		c.Meta = make(map[string]any, len(e.Meta))
+		for k, v := range e.Meta {
+			c.Meta[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,161 @@
+package errorsx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/velmie/x/svc/errorsx"
+	"github.com/velmie/x/svc/http/response"
+)
+
+type stringError string
+
+func (e stringError) Error() string {
+	return string(e)
+}
+
+const errBadToken = stringError("bad token")
+
+type orderError struct {
+	OrderID string
+}
+
+func (e *orderError) Error() string {
+	return "order " + e.OrderID + " is closed"
+}
+
+type fieldErrors []*response.HTTPError
+
+func (fieldErrors) Error() string {
+	return "invalid fields"
+}
+
+func (e fieldErrors) HTTPErrors() []*response.HTTPError {
+	return e
+}
+
+func TestMapper_Map(t *testing.T) {
+	mapper := errorsx.NewMapper(errorsx.DefaultRules()).With(
+		errorsx.Sentinel(errBadToken, &response.HTTPError{
+			Code:       response.ErrCodeUnauthorized,
+			Target:     response.TargetCommon,
+			StatusCode: http.StatusUnauthorized,
+		}),
+		errorsx.Type(func(err *orderError) *response.HTTPError {
+			return &response.HTTPError{
+				Code:       "ORDER_CLOSED",
+				Meta:       map[string]any{"orderId": err.OrderID},
+				Target:     response.TargetCommon,
+				StatusCode: http.StatusConflict,
+			}
+		}),
+	)
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedCode   string
+		expectedStatus int
+	}{
+		{
+			name:           "Sentinel error",
+			err:            fmt.Errorf("cannot authenticate: %w", errBadToken),
+			expectedCode:   response.ErrCodeUnauthorized,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Typed error",
+			err:            fmt.Errorf("cannot pay: %w", &orderError{OrderID: "42"}),
+			expectedCode:   "ORDER_CLOSED",
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "HTTPError provider",
+			err:            fmt.Errorf("wrapped: %w", &errorsx.PermissionError{Action: "read"}),
+			expectedCode:   response.ErrCodeForbidden,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Outermost error wins",
+			err:            &errorsx.AuthenticationError{Cause: fmt.Errorf("lookup: %w", sql.ErrNoRows)},
+			expectedCode:   response.ErrCodeUnauthorized,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "sql.ErrNoRows",
+			err:            fmt.Errorf("get user: %w", sql.ErrNoRows),
+			expectedCode:   response.ErrCodeNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Context deadline",
+			err:            fmt.Errorf("query: %w", context.DeadlineExceeded),
+			expectedCode:   response.ErrCodeInternalServerError,
+			expectedStatus: http.StatusGatewayTimeout,
+		},
+		{
+			name:           "Unknown error falls back to internal server error",
+			err:            errors.New("connection refused by 10.0.0.1"),
+			expectedCode:   response.ErrCodeInternalServerError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpErr := mapper.Map(tt.err)
+			if httpErr.Code != tt.expectedCode || httpErr.StatusCode != tt.expectedStatus {
+				t.Errorf("expected %s/%d, got %s/%d", tt.expectedCode, tt.expectedStatus, httpErr.Code, httpErr.StatusCode)
+			}
+			if httpErr.Title != "" {
+				t.Errorf("internal message must not be exposed, got title %q", httpErr.Title)
+			}
+		})
+	}
+}
+
+func TestMapper_SentinelReturnsCopy(t *testing.T) {
+	mapper := errorsx.NewMapper(errorsx.SQLRules())
+
+	first := mapper.Map(sql.ErrNoRows)
+	first.Title = "changed"
+
+	if second := mapper.Map(sql.ErrNoRows); second.Title != "" {
+		t.Errorf("rule template must not be modified, got title %q", second.Title)
+	}
+}
+
+func TestMapper_MapAll(t *testing.T) {
+	mapper := errorsx.NewMapper(errorsx.DefaultRules())
+
+	violations := fieldErrors{
+		{Code: response.ErrCodeInvalidRequestParameter, Source: "/name", Target: response.TargetField},
+		{Code: response.ErrCodeRequiredRequestParameter, Source: "/email", Target: response.TargetField},
+	}
+	if actual := mapper.MapAll(fmt.Errorf("bind: %w", violations)); len(actual) != 2 {
+		t.Errorf("expected 2 errors, got %d", len(actual))
+	}
+
+	actual := mapper.MapAll(sql.ErrNoRows)
+	if len(actual) != 1 || actual[0].Code != response.ErrCodeNotFound {
+		t.Errorf("unexpected result: %v", actual)
+	}
+
+	if mapper.MapAll(nil) != nil || mapper.Map(nil) != nil {
+		t.Error("nil error must be mapped to nil")
+	}
+}
+
+func TestMapper_WithFallback(t *testing.T) {
+	mapper := errorsx.NewMapper(nil, errorsx.WithFallback(func(err error) *response.HTTPError {
+		return &response.HTTPError{Code: "UNKNOWN", StatusCode: http.StatusTeapot}
+	}))
+
+	if actual := mapper.Map(errors.New("x")); actual.Code != "UNKNOWN" {
+		t.Errorf("expected fallback to be used, got %s", actual.Code)
+	}
+}
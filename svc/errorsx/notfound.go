@@ -0,0 +1,57 @@
+package errorsx
+
+import (
+	"net/http"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// NotFoundError represents an error due to a missing resource.
+type NotFoundError struct {
+	ResourceName string // The name of the resource which was not found, e.g. "order".
+	ResourceID   string // The unique identifier of the resource.
+	Cause        error  // The underlying error which caused this error, e.g. sql.ErrNoRows.
+}
+
+// Error satisfies the error interface for NotFoundError.
+func (n *NotFoundError) Error() string {
+	message := "resource not found"
+	if n.ResourceName != "" {
+		message = n.ResourceName + " not found"
+	}
+	if n.ResourceID != "" {
+		message = message + ": " + n.ResourceID
+	}
+	if n.Cause != nil {
+		message = message + ": " + n.Cause.Error()
+	}
+	return message
+}
+
+func (n *NotFoundError) HTTPError() *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeNotFound,
+		Meta:       resourceMeta(n.ResourceName, n.ResourceID),
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+func (n *NotFoundError) Unwrap() error {
+	return n.Cause
+}
+
+// resourceMeta builds HTTP error metadata describing the resource, nil is returned if there is nothing to describe
+func resourceMeta(name, id string) map[string]any {
+	if name == "" && id == "" {
+		return nil
+	}
+	meta := make(map[string]any, 2)
+	if name != "" {
+		meta["resource"] = name
+	}
+	if id != "" {
+		meta["id"] = id
+	}
+	return meta
+}
@@ -0,0 +1,80 @@
+package errorsx
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// RateLimitError represents an error due to too many requests made in a given amount of time.
+type RateLimitError struct {
+	Limit      int           // The maximum number of requests allowed within the window, 0 if not specified.
+	Window     time.Duration // The time window the limit applies to, 0 if not specified.
+	RetryAfter time.Duration // The duration after which the request could be retried, 0 if unknown.
+	Cause      error         // The underlying error which caused this error.
+}
+
+// Error satisfies the error interface for RateLimitError.
+func (r *RateLimitError) Error() string {
+	message := "rate limit exceeded"
+	if r.Limit > 0 {
+		message = message + fmt.Sprintf(": %d requests", r.Limit)
+		if r.Window > 0 {
+			message = message + " per " + r.Window.String()
+		}
+	}
+	if r.RetryAfter > 0 {
+		message = message + ", retry after " + r.RetryAfter.String()
+	}
+	if r.Cause != nil {
+		message = message + ": " + r.Cause.Error()
+	}
+	return message
+}
+
+func (r *RateLimitError) HTTPError() *response.HTTPError {
+	meta := make(map[string]any, 3)
+	if r.Limit > 0 {
+		meta["limit"] = r.Limit
+	}
+	if r.Window > 0 {
+		meta["window"] = seconds(r.Window)
+	}
+	if r.RetryAfter > 0 {
+		meta["retryAfter"] = seconds(r.RetryAfter)
+	}
+	if len(meta) == 0 {
+		meta = nil
+	}
+	return &response.HTTPError{
+		Code:       response.ErrCodeRateLimitExceeded,
+		Meta:       meta,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusTooManyRequests,
+	}
+}
+
+// HTTPHeaders returns Retry-After and X-RateLimit-Limit headers
+func (r *RateLimitError) HTTPHeaders() http.Header {
+	h := make(http.Header, 2)
+	if r.RetryAfter > 0 {
+		h.Set("Retry-After", strconv.Itoa(seconds(r.RetryAfter)))
+	}
+	if r.Limit > 0 {
+		h.Set("X-RateLimit-Limit", strconv.Itoa(r.Limit))
+	}
+	return h
+}
+
+func (r *RateLimitError) Unwrap() error {
+	return r.Cause
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,42 @@
+package errorsx
+
+import (
+	"net/http"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// HTTPHeadersProvider is implemented by errors which require additional response headers, e.g. Retry-After
+type HTTPHeadersProvider interface {
+	HTTPHeaders() http.Header
+}
+
+// ErrorsWriter writes HTTP errors to the response, it is implemented by response.ErrorWriter
+type ErrorsWriter interface {
+	Write(w http.ResponseWriter, r *http.Request, errs ...*response.HTTPError) error
+}
+
+// Headers collects headers of all errors of the chain implementing HTTPHeadersProvider.
+// Headers of outer errors take precedence
+func Headers(err error) http.Header {
+	h := make(http.Header)
+	UnwrapF(err, func(target error) bool {
+		if p, ok := target.(HTTPHeadersProvider); ok {
+			for k, v := range p.HTTPHeaders() {
+				if _, exists := h[k]; !exists {
+					h[k] = v
+				}
+			}
+		}
+		return false
+	})
+	return h
+}
+
+// Render maps the error using MapAll, sets headers provided by the error chain and writes the result
+func (m *Mapper) Render(w http.ResponseWriter, r *http.Request, ew ErrorsWriter, err error) error {
+	for k, v := range Headers(err) {
+		w.Header()[k] = v
+	}
+	return ew.Write(w, r, m.MapAll(err)...)
+}
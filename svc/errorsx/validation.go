@@ -0,0 +1,89 @@
+package errorsx
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// Violation describes a single invalid field.
+type Violation struct {
+	Field   string         // The field reference, either a JSON pointer (e.g. "/address/city") or a parameter name.
+	Code    string         // The error code, response.ErrCodeInvalidRequestParameter is used if empty.
+	Message string         // The internal description of the violation, it is not exposed to clients.
+	Meta    map[string]any // Additional metadata exposed to clients, e.g. rule parameters.
+}
+
+// ValidationError represents an error due to one or multiple invalid fields.
+type ValidationError struct {
+	Violations []Violation // The list of field violations.
+	Cause      error       // The underlying error which caused this error.
+}
+
+// Error satisfies the error interface for ValidationError.
+func (v *ValidationError) Error() string {
+	message := "validation failed"
+	if len(v.Violations) > 0 {
+		items := make([]string, 0, len(v.Violations))
+		for _, violation := range v.Violations {
+			item := violation.Field
+			if violation.Message != "" {
+				item = item + " " + violation.Message
+			}
+			items = append(items, item)
+		}
+		message = message + ": " + strings.Join(items, "; ")
+	}
+	if v.Cause != nil {
+		message = message + ": " + v.Cause.Error()
+	}
+	return message
+}
+
+// HTTPError returns the common error which does not describe particular fields,
+// use HTTPErrors in order to get an error per violation.
+func (v *ValidationError) HTTPError() *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeInvalidRequestParameter,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// HTTPErrors returns an error with the field target per violation.
+func (v *ValidationError) HTTPErrors() []*response.HTTPError {
+	httpErrs := make([]*response.HTTPError, 0, len(v.Violations))
+	for _, violation := range v.Violations {
+		code := violation.Code
+		if code == "" {
+			code = response.ErrCodeInvalidRequestParameter
+		}
+		httpErrs = append(httpErrs, &response.HTTPError{
+			Code:       code,
+			Source:     violation.Field,
+			Meta:       violation.Meta,
+			Target:     response.TargetField,
+			StatusCode: http.StatusBadRequest,
+		})
+	}
+	return httpErrs
+}
+
+// Add appends the violation and returns the error itself, so calls could be chained.
+func (v *ValidationError) Add(field, message string, meta map[string]any) *ValidationError {
+	v.Violations = append(v.Violations, Violation{Field: field, Message: message, Meta: meta})
+	return v
+}
+
+// Err returns the error if there is at least one violation, nil otherwise.
+func (v *ValidationError) Err() error {
+	if len(v.Violations) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v *ValidationError) Unwrap() error {
+	return v.Cause
+}
@@ -0,0 +1,622 @@
+package binding
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+const (
+	tagQuery    = "query"
+	tagPath     = "path"
+	tagJSON     = "json"
+	tagValidate = "validate"
+)
+
+var errUnsupportedType = errors.New("unsupported field type")
+
+// DefaultBinder is used by the package level Bind and Validate functions
+var DefaultBinder = NewBinder()
+
+// PathParamFunc retrieves the path parameter value by its name.
+// The second returned value reports whether the parameter is present
+type PathParamFunc func(r *http.Request, name string) (string, bool)
+
+// Option configures Binder
+type Option func(b *Binder)
+
+// WithPathParamFunc sets the function used in order to retrieve path parameters,
+// it allows to integrate the binder with any router
+func WithPathParamFunc(f PathParamFunc) Option {
+	return func(b *Binder) {
+		b.pathParam = f
+	}
+}
+
+// WithRule adds a custom validation rule which could be referenced in the validate tag by the given name.
+// Built-in rules could be overridden as well
+func WithRule(name string, rule RuleFunc) Option {
+	return func(b *Binder) {
+		b.rules[name] = rule
+	}
+}
+
+// WithDisallowUnknownFields makes the binder reject request bodies containing fields
+// which do not match any destination struct field
+func WithDisallowUnknownFields() Option {
+	return func(b *Binder) {
+		b.disallowUnknownFields = true
+	}
+}
+
+// Binder decodes a JSON body, query and path parameters into a struct and validates the result.
+//
+// Struct fields are bound as follows:
+//   - fields tagged with `query:"name"` are taken from the URL query
+//   - fields tagged with `path:"name"` are taken from the path parameters
+//   - other exported fields are decoded from the JSON body (the json tag is respected)
+//
+// Validation rules are declared with the validate tag, e.g. `validate:"required;min(1);max(100)"`.
+// Every violation is reported as response.HTTPError within Errors
+type Binder struct {
+	pathParam             PathParamFunc
+	rules                 map[string]RuleFunc
+	disallowUnknownFields bool
+}
+
+// NewBinder creates a new Binder
+func NewBinder(opts ...Option) *Binder {
+	b := &Binder{
+		pathParam: DefaultPathParam,
+		rules:     defaultRules(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Bind binds the request using DefaultBinder
+func Bind(r *http.Request, dst any) error {
+	return DefaultBinder.Bind(r, dst)
+}
+
+// Validate validates the struct using DefaultBinder
+func Validate(v any) error {
+	return DefaultBinder.Validate(v)
+}
+
+// DefaultPathParam retrieves path parameters populated by the standard http.ServeMux (Go 1.22+)
+func DefaultPathParam(r *http.Request, name string) (string, bool) {
+	pv, ok := any(r).(interface{ PathValue(name string) string })
+	if !ok {
+		return "", false
+	}
+	value := pv.PathValue(name)
+	return value, value != ""
+}
+
+// Bind decodes the request into dst which must be a pointer to a struct.
+// The JSON body is decoded only if the struct has at least one body field,
+// query and path parameter fields are never set from the body.
+// Binding and validation violations are returned as Errors,
+// any other error means improper usage, e.g. an unknown rule or an unsupported field type
+func (b *Binder) Bind(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binding: dst must be a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+
+	body := &bodyKeys{}
+	if hasBodyFields(v.Type()) {
+		var httpErr *response.HTTPError
+		if body.node, httpErr = b.decodeBody(r, v); httpErr != nil {
+			return Errors{httpErr}
+		}
+		body.known = true
+	}
+
+	var errs Errors
+	if err := b.bindParams(r, v, &errs); err != nil {
+		return err
+	}
+	if err := b.validateStruct(v, "", body, &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate checks the rules of the body fields of the given struct.
+// It could be used in order to validate payloads which were not decoded by the Binder.
+// Since it is unknown which fields were sent, required non-pointer fields must not be the zero value
+func (b *Binder) Validate(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("binding: value must be a struct or a pointer to a struct, got %T", v)
+	}
+	var errs Errors
+	if err := b.validateStruct(rv, "", &bodyKeys{}, &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decodeBody decodes the body fields of v and returns the generic representation of the body
+// which is used in order to check whether a field was sent
+func (b *Binder) decodeBody(r *http.Request, v reflect.Value) (any, *response.HTTPError) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, missingBodyError()
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, invalidBodyError()
+	}
+
+	// the body is decoded into the copy, so it cannot set parameter fields
+	tmp := reflect.New(v.Type())
+	tmp.Elem().Set(v)
+	if httpErr := b.decode(data, tmp.Interface()); httpErr != nil {
+		return nil, httpErr
+	}
+	copyBodyFields(v, tmp.Elem())
+
+	var node any
+	if err = json.NewDecoder(bytes.NewReader(data)).Decode(&node); err != nil {
+		return nil, invalidBodyError()
+	}
+	return node, nil
+}
+
+func (b *Binder) decode(data []byte, dst any) *response.HTTPError {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if b.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return missingBodyError()
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return syntaxError(nil)
+	case errors.As(err, &syntaxErr):
+		return syntaxError(map[string]any{"offset": syntaxErr.Offset})
+	case errors.As(err, &typeErr):
+		return invalidFieldError(dottedPathToPointer(typeErr.Field), map[string]any{"type": typeName(typeErr.Type)})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json does not provide a typed error for unknown fields
+		name, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		httpErr := invalidBodyError()
+		httpErr.Source = jsonPointer("", name)
+		httpErr.Target = response.TargetField
+		return httpErr
+	default:
+		return invalidBodyError()
+	}
+}
+
+func (b *Binder) bindParams(r *http.Request, v reflect.Value, errs *Errors) error {
+	query := r.URL.Query()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+
+		if field.Anonymous && isPlainStruct(field.Type) && !hasParamTag(field) {
+			if err := b.bindParams(r, fv, errs); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		var (
+			name   string
+			values []string
+		)
+		if name = paramName(field, tagQuery); name != "" {
+			values = query[name]
+		} else if name = paramName(field, tagPath); name != "" {
+			if value, ok := b.pathParam(r, name); ok {
+				values = []string{value}
+			}
+		} else {
+			continue
+		}
+
+		// an empty parameter e.g. "?page=" is considered as not provided
+		present := len(values) > 0 && !(len(values) == 1 && values[0] == "")
+		if present {
+			if err := setValues(fv, values); err != nil {
+				if errors.Is(err, errUnsupportedType) {
+					return fmt.Errorf("binding: field %s: %w", field.Name, err)
+				}
+				*errs = append(*errs, invalidFieldError(name, map[string]any{"type": typeName(field.Type)}))
+				continue
+			}
+		}
+
+		if err := b.checkField(field, fv, name, present, present, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Binder) validateStruct(v reflect.Value, pointer string, body *bodyKeys, errs *Errors) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+
+		if hasParamTag(field) {
+			continue
+		}
+
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && isPlainStruct(field.Type) && name == "" {
+			if err := b.validateStruct(fv, pointer, body, errs); err != nil {
+				return err
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		source := jsonPointer(pointer, name)
+		child := body.member(name)
+		present, provided := !isEmpty(fv), !fv.IsZero()
+		switch {
+		case fv.Kind() == reflect.Ptr:
+			present, provided = !fv.IsNil(), !fv.IsNil()
+		case body.known:
+			present, provided = child.sent(), child.sent()
+		}
+		if err := b.checkField(field, fv, source, present, provided, errs); err != nil {
+			return err
+		}
+		if !(fv.Kind() == reflect.Ptr && fv.IsNil()) {
+			if err := b.validateNested(reflect.Indirect(fv), source, child, errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *Binder) validateNested(v reflect.Value, pointer string, body *bodyKeys, errs *Errors) error {
+	switch {
+	case isPlainStruct(v.Type()):
+		return b.validateStruct(v, pointer, body, errs)
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Ptr && item.IsNil() {
+				continue
+			}
+			source := jsonPointer(pointer, strconv.Itoa(i))
+			if err := b.validateNested(reflect.Indirect(item), source, body.item(i), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkField applies the rules declared in the validate tag, the first violation is reported only.
+// The required rule is satisfied if the value is provided, other rules are applied to present values only
+func (b *Binder) checkField(
+	field reflect.StructField,
+	fv reflect.Value,
+	source string,
+	present, provided bool,
+	errs *Errors,
+) error {
+	tag, ok := field.Tag.Lookup(tagValidate)
+	if !ok {
+		return nil
+	}
+	directives, err := ParseDirectives(tag)
+	if err != nil {
+		return fmt.Errorf("binding: field %s: %w", field.Name, err)
+	}
+
+	value := reflect.Indirect(fv)
+	for _, d := range directives {
+		if d.Name == ruleRequired {
+			if !provided {
+				*errs = append(*errs, requiredFieldError(source))
+				return nil
+			}
+			continue
+		}
+		if !present {
+			continue
+		}
+
+		rule, found := b.rules[d.Name]
+		if !found {
+			return fmt.Errorf("binding: field %s: unknown validation rule %q", field.Name, d.Name)
+		}
+		valid, err := rule(value, d.Params)
+		if err != nil {
+			return fmt.Errorf("binding: field %s: %w", field.Name, err)
+		}
+		if !valid {
+			*errs = append(*errs, invalidFieldError(source, ruleMeta(d.Name, d.Params)))
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// isEmpty reports whether the value validated by Validate is considered as not provided:
+// nil pointers, empty strings, slices and maps.
+// Rules other than required are not applied to such values
+func isEmpty(fv reflect.Value) bool {
+	if fv.Kind() == reflect.Ptr {
+		return fv.IsNil()
+	}
+	switch fv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	default:
+		return false
+	}
+}
+
+// bodyKeys is the generic representation of the JSON body used in order to check whether a field was sent.
+// The body is unknown if the struct is checked by Validate
+type bodyKeys struct {
+	node  any
+	known bool
+}
+
+// member returns the object member, the key is matched case-insensitively like encoding/json does
+func (k *bodyKeys) member(name string) *bodyKeys {
+	child := &bodyKeys{known: k.known}
+	obj, ok := k.node.(map[string]any)
+	if !ok {
+		return child
+	}
+	if value, ok := obj[name]; ok {
+		child.node = value
+		return child
+	}
+	for key, value := range obj {
+		if strings.EqualFold(key, name) {
+			child.node = value
+			return child
+		}
+	}
+	return child
+}
+
+func (k *bodyKeys) item(i int) *bodyKeys {
+	child := &bodyKeys{known: k.known}
+	if arr, ok := k.node.([]any); ok && i < len(arr) {
+		child.node = arr[i]
+	}
+	return child
+}
+
+// sent reports whether the value was sent, null is considered as not sent
+func (k *bodyKeys) sent() bool {
+	return k.node != nil
+}
+
+// copyBodyFields copies the fields which are decoded from the body
+func copyBodyFields(dst, src reflect.Value) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if hasParamTag(field) {
+			continue
+		}
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && isPlainStruct(field.Type) && name == "" {
+			copyBodyFields(dst.Field(i), src.Field(i))
+			continue
+		}
+		if field.IsExported() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+}
+
+func setValues(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Ptr {
+		elem := reflect.New(fv.Type().Elem())
+		if err := setValues(elem.Elem(), values); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	}
+
+	if fv.Kind() == reflect.Slice && !isTextUnmarshaler(fv) {
+		items := reflect.MakeSlice(fv.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValues(items.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		fv.Set(items)
+		return nil
+	}
+
+	return setScalar(fv, values[0])
+}
+
+func setScalar(fv reflect.Value, value string) error {
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	if isTextUnmarshaler(fv) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedType, fv.Type())
+	}
+
+	return nil
+}
+
+func isTextUnmarshaler(fv reflect.Value) bool {
+	return fv.CanAddr() && fv.Addr().Type().Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+}
+
+// typeName returns the JSON-like type name which is exposed to clients
+func typeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return t.String()
+	}
+}
+
+func hasBodyFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if hasParamTag(field) {
+			continue
+		}
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && isPlainStruct(field.Type) && name == "" {
+			if hasBodyFields(field.Type) {
+				return true
+			}
+			continue
+		}
+		if field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+func hasParamTag(field reflect.StructField) bool {
+	return paramName(field, tagQuery) != "" || paramName(field, tagPath) != ""
+}
+
+func paramName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	return name
+}
+
+func jsonName(field reflect.StructField) (name string, skip bool) {
+	tag := field.Tag.Get(tagJSON)
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	return name, false
+}
+
+// isPlainStruct reports whether the type is a struct which fields should be walked through
+func isPlainStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	if reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()) {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem())
+}
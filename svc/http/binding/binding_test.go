@@ -0,0 +1,341 @@
+package binding_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/velmie/x/svc/http/binding"
+	"github.com/velmie/x/svc/http/response"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"regexp(^[0-9]{5}$)"`
+}
+
+type createUserRequest struct {
+	ID       int64    `path:"id" validate:"required;min(1)"`
+	Page     *int     `query:"page" validate:"range(1,100)"`
+	Tags     []string `query:"tag" validate:"oneOf(a,b,c)"`
+	Name     string   `json:"name" validate:"required;min(2);max(5)"`
+	Role     string   `json:"role" validate:"oneOf(admin,user)"`
+	Address  *address `json:"address"`
+	Contacts []address
+}
+
+func pathParams(params map[string]string) PathParamFunc {
+	return func(_ *http.Request, name string) (string, bool) {
+		v, ok := params[name]
+		return v, ok
+	}
+}
+
+func newRequest(target, body string) *http.Request {
+	if body == "" {
+		return httptest.NewRequest(http.MethodPost, target, http.NoBody)
+	}
+	return httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+}
+
+func TestBinder_Bind_Success(t *testing.T) {
+	b := NewBinder(WithPathParamFunc(pathParams(map[string]string{"id": "42"})))
+	r := newRequest(
+		"/users/42?page=3&tag=a&tag=c",
+		`{"name":"john","role":"admin","address":{"city":"Riga","zip":"12345"}}`,
+	)
+
+	var req createUserRequest
+	if err := b.Bind(r, &req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if req.ID != 42 {
+		t.Errorf("expected id 42, got %d", req.ID)
+	}
+	if req.Page == nil || *req.Page != 3 {
+		t.Errorf("expected page 3, got %v", req.Page)
+	}
+	if !reflect.DeepEqual(req.Tags, []string{"a", "c"}) {
+		t.Errorf("expected tags [a c], got %v", req.Tags)
+	}
+	if req.Name != "john" || req.Address.City != "Riga" {
+		t.Errorf("unexpected body binding result: %+v", req)
+	}
+}
+
+func TestBinder_Bind_Violations(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		params   map[string]string
+		expected []*response.HTTPError
+	}{
+		{
+			name:   "Missing body",
+			target: "/users/1",
+			params: map[string]string{"id": "1"},
+			expected: []*response.HTTPError{
+				{Code: response.ErrCodeMissingRequestBody, Target: response.TargetCommon},
+			},
+		},
+		{
+			name:   "JSON syntax error",
+			target: "/users/1",
+			body:   `{"name":}`,
+			params: map[string]string{"id": "1"},
+			expected: []*response.HTTPError{
+				{Code: response.ErrCodeJSONSyntax, Target: response.TargetCommon, Meta: map[string]any{"offset": int64(9)}},
+			},
+		},
+		{
+			name:   "JSON type mismatch",
+			target: "/users/1",
+			body:   `{"name":"john","address":{"city":1}}`,
+			params: map[string]string{"id": "1"},
+			expected: []*response.HTTPError{
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "/address/city",
+					Target: response.TargetField,
+					Meta:   map[string]any{"type": "string"},
+				},
+			},
+		},
+		{
+			name:   "Parameters and body fields violations",
+			target: "/users/abc?page=0&tag=x",
+			body:   `{"name":"j","role":"guest","address":{"zip":"1"},"Contacts":[{"city":"Riga"},{"zip":null}]}`,
+			params: map[string]string{"id": "abc"},
+			expected: []*response.HTTPError{
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "id",
+					Target: response.TargetField,
+					Meta:   map[string]any{"type": "integer"},
+				},
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "page",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "range", "range": []any{int64(1), int64(100)}},
+				},
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "tag",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "oneOf", "oneOf": []any{"a", "b", "c"}},
+				},
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "/name",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "min", "min": int64(2)},
+				},
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "/role",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "oneOf", "oneOf": []any{"admin", "user"}},
+				},
+				{
+					Code:   response.ErrCodeRequiredRequestParameter,
+					Source: "/address/city",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "required"},
+				},
+				{
+					Code:   response.ErrCodeInvalidRequestParameter,
+					Source: "/address/zip",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "regexp", "regexp": "^[0-9]{5}$"},
+				},
+				{
+					Code:   response.ErrCodeRequiredRequestParameter,
+					Source: "/Contacts/1/city",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "required"},
+				},
+			},
+		},
+		{
+			name:   "Required path parameter is missing",
+			target: "/users",
+			body:   `{"name":"john"}`,
+			expected: []*response.HTTPError{
+				{
+					Code:   response.ErrCodeRequiredRequestParameter,
+					Source: "id",
+					Target: response.TargetField,
+					Meta:   map[string]any{"rule": "required"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBinder(WithPathParamFunc(pathParams(tt.params)))
+
+			var req createUserRequest
+			err := b.Bind(newRequest(tt.target, tt.body), &req)
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected binding errors, got %v", err)
+			}
+			if len(errs) != len(tt.expected) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.expected), len(errs), errs)
+			}
+			for i, expected := range tt.expected {
+				actual := errs[i]
+				if actual.StatusCode != http.StatusBadRequest {
+					t.Errorf("expected status code %d, got %d", http.StatusBadRequest, actual.StatusCode)
+				}
+				actual.StatusCode = 0
+				if !reflect.DeepEqual(expected, actual) {
+					t.Errorf("error #%d: expected %+v, got %+v", i, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestBinder_Bind_RequiredZeroValues(t *testing.T) {
+	type request struct {
+		Count  *int   `json:"count" validate:"required"`
+		Total  int    `json:"total" validate:"required"`
+		Active bool   `json:"active" validate:"required"`
+		Note   string `json:"note" validate:"required"`
+		Offset int    `query:"offset" validate:"required"`
+	}
+
+	var req request
+	err := NewBinder().Bind(newRequest("/items?offset=0", `{"count":0,"total":0,"active":false,"note":""}`), &req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Count == nil || *req.Count != 0 {
+		t.Errorf("expected count 0, got %v", req.Count)
+	}
+
+	err = NewBinder().Bind(newRequest("/items", `{"count":null,"total":null}`), &req)
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected binding errors, got %v", err)
+	}
+	var sources []string
+	for _, e := range errs {
+		if e.Code != response.ErrCodeRequiredRequestParameter {
+			t.Errorf("expected required error, got %+v", e)
+		}
+		sources = append(sources, e.Source)
+	}
+	expected := []string{"offset", "/count", "/total", "/active", "/note"}
+	if !reflect.DeepEqual(expected, sources) {
+		t.Errorf("expected sources %v, got %v", expected, sources)
+	}
+}
+
+func TestBinder_Bind_ParamsAreNotSetFromBody(t *testing.T) {
+	type request struct {
+		Page int    `query:"page"`
+		ID   string `path:"id"`
+		Name string `json:"name"`
+	}
+
+	req := request{Page: 1}
+	err := NewBinder().Bind(newRequest("/items", `{"Page":77,"ID":"x","name":"john"}`), &req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Page != 1 || req.ID != "" || req.Name != "john" {
+		t.Errorf("unexpected binding result: %+v", req)
+	}
+}
+
+func TestValidate_RequiredZeroValue(t *testing.T) {
+	type payload struct {
+		Count *int `json:"count" validate:"required"`
+		Total int  `json:"total" validate:"required"`
+	}
+
+	zero := 0
+	err := Validate(payload{Count: &zero})
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Source != "/total" {
+		t.Fatalf("expected the required error for /total only, got %v", err)
+	}
+}
+
+func TestBinder_Bind_QueryOnly(t *testing.T) {
+	type listRequest struct {
+		Limit  uint   `query:"limit" validate:"max(50)"`
+		Search string `query:"q"`
+	}
+
+	var req listRequest
+	err := Bind(httptest.NewRequest(http.MethodGet, "/items?limit=10&q=foo", http.NoBody), &req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Limit != 10 || req.Search != "foo" {
+		t.Errorf("unexpected binding result: %+v", req)
+	}
+}
+
+func TestBinder_Bind_UnknownField(t *testing.T) {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	b := NewBinder(WithDisallowUnknownFields())
+	err := b.Bind(newRequest("/", `{"name":"john","age":1}`), &request{})
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected binding errors, got %v", err)
+	}
+	if errs[0].Code != response.ErrCodeInvalidRequestBody || errs[0].Source != "/age" {
+		t.Errorf("unexpected error: %+v", errs[0])
+	}
+}
+
+func TestBinder_Bind_CustomRule(t *testing.T) {
+	type request struct {
+		Email string `json:"email" validate:"email"`
+	}
+
+	b := NewBinder(WithRule("email", func(value reflect.Value, _ []string) (bool, error) {
+		return strings.Contains(value.String(), "@"), nil
+	}))
+
+	err := b.Bind(newRequest("/", `{"email":"john"}`), &request{})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected binding errors, got %v", err)
+	}
+	if errs[0].Source != "/email" || errs[0].Meta["rule"] != "email" {
+		t.Errorf("unexpected error: %+v", errs[0])
+	}
+}
+
+func TestBinder_Bind_ImproperUsage(t *testing.T) {
+	type unknownRule struct {
+		Name string `json:"name" validate:"unknown"`
+	}
+
+	err := Bind(newRequest("/", `{"name":"john"}`), &unknownRule{})
+	var errs Errors
+	if err == nil || errors.As(err, &errs) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+
+	if err = Bind(newRequest("/", `{}`), unknownRule{}); err == nil {
+		t.Fatal("expected error for non-pointer destination")
+	}
+}
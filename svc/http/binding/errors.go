@@ -0,0 +1,124 @@
+package binding
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// Errors is a set of request binding violations.
+// Each violation is represented by the response.HTTPError which could be sent to the client as is
+type Errors []*response.HTTPError
+
+// Error satisfies the error interface for Errors
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, httpErr := range e {
+		messages = append(messages, httpErr.Error())
+	}
+	return "binding failed: " + strings.Join(messages, "; ")
+}
+
+// HTTPErrors returns violations as a list of response.HTTPError
+func (e Errors) HTTPErrors() []*response.HTTPError {
+	return e
+}
+
+// Response builds the response payload from the violations
+func (e Errors) Response() response.Errors {
+	return response.Error(e...)
+}
+
+func missingBodyError() *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeMissingRequestBody,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func syntaxError(meta map[string]any) *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeJSONSyntax,
+		Meta:       meta,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func invalidBodyError() *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeInvalidRequestBody,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func invalidFieldError(source string, meta map[string]any) *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeInvalidRequestParameter,
+		Source:     source,
+		Meta:       meta,
+		Target:     response.TargetField,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+func requiredFieldError(source string) *response.HTTPError {
+	return &response.HTTPError{
+		Code:       response.ErrCodeRequiredRequestParameter,
+		Source:     source,
+		Meta:       map[string]any{"rule": ruleRequired},
+		Target:     response.TargetField,
+		StatusCode: http.StatusBadRequest,
+	}
+}
+
+// ruleMeta builds error metadata carrying the rule name and its parameters
+func ruleMeta(name string, params []string) map[string]any {
+	meta := map[string]any{"rule": name}
+	switch len(params) {
+	case 0:
+	case 1:
+		meta[name] = metaParam(params[0])
+	default:
+		values := make([]any, 0, len(params))
+		for _, p := range params {
+			values = append(values, metaParam(p))
+		}
+		meta[name] = values
+	}
+	return meta
+}
+
+// metaParam represents numeric parameters as numbers so that clients are able to use them as is
+func metaParam(p string) any {
+	if i, err := strconv.ParseInt(p, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(p, 64); err == nil {
+		return f
+	}
+	return p
+}
+
+// jsonPointer appends the reference token to the given JSON pointer (RFC 6901)
+func jsonPointer(pointer, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return pointer + "/" + token
+}
+
+// dottedPathToPointer converts encoding/json field path e.g. "user.address.city" to the JSON pointer
+func dottedPathToPointer(path string) string {
+	if path == "" {
+		return ""
+	}
+	var pointer string
+	for _, token := range strings.Split(path, ".") {
+		pointer = jsonPointer(pointer, token)
+	}
+	return pointer
+}
@@ -0,0 +1,229 @@
+package binding
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	ruleRequired = "required"
+	ruleNotEmpty = "notEmpty"
+	ruleMin      = "min"
+	ruleMax      = "max"
+	ruleRange    = "range"
+	ruleOneOf    = "oneOf"
+	ruleRegexp   = "regexp"
+)
+
+// RuleFunc checks whether the given value satisfies the rule with the given parameters.
+// The value is never a pointer, pointers are dereferenced before the check.
+// Returned error indicates improper rule usage (e.g. invalid parameters) rather than a violation
+type RuleFunc func(value reflect.Value, params []string) (ok bool, err error)
+
+// Directive is a single validation rule declared in the struct tag, e.g. min(3)
+type Directive struct {
+	Name   string
+	Params []string
+}
+
+// ParseDirectives parses the validation tag.
+// The syntax is the same as for envx directives: rules are separated by a semicolon,
+// parameters are enclosed in parentheses and separated by a comma, a backslash escapes the next character.
+//
+// Example:
+//
+//	`validate:"required;min(3);oneOf(draft,published)"`
+func ParseDirectives(tag string) ([]Directive, error) {
+	var directives []Directive
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := parseDirective(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid directive %q: %w", part, err)
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+func parseDirective(directive string) (Directive, error) {
+	paramStart := strings.Index(directive, "(")
+	if paramStart == -1 {
+		return Directive{Name: directive}, nil
+	}
+
+	name := strings.TrimSpace(directive[:paramStart])
+	paramEnd := strings.LastIndex(directive, ")")
+	if paramEnd == -1 || paramEnd <= paramStart || name == "" {
+		return Directive{}, fmt.Errorf("invalid directive format: %s", directive)
+	}
+
+	var (
+		params       []string
+		currentParam strings.Builder
+		escaped      bool
+	)
+	for _, c := range directive[paramStart+1 : paramEnd] {
+		if escaped {
+			currentParam.WriteRune(c)
+			escaped = false
+			continue
+		}
+		if c == '\\' {
+			escaped = true
+			continue
+		}
+		if c == ',' {
+			params = append(params, currentParam.String())
+			currentParam.Reset()
+			continue
+		}
+		currentParam.WriteRune(c)
+	}
+	if currentParam.Len() > 0 {
+		params = append(params, currentParam.String())
+	}
+
+	return Directive{Name: name, Params: params}, nil
+}
+
+func defaultRules() map[string]RuleFunc {
+	return map[string]RuleFunc{
+		ruleNotEmpty: NotEmptyRule,
+		ruleMin:      MinRule,
+		ruleMax:      MaxRule,
+		ruleRange:    RangeRule,
+		ruleOneOf:    OneOfRule,
+		ruleRegexp:   RegexpRule,
+	}
+}
+
+// NotEmptyRule checks that the value is not the zero value of its type
+func NotEmptyRule(value reflect.Value, _ []string) (bool, error) {
+	return !value.IsZero(), nil
+}
+
+// MinRule checks that the value is greater than or equal to the parameter.
+// String length is checked for strings and number of items for slices, arrays and maps
+func MinRule(value reflect.Value, params []string) (bool, error) {
+	if len(params) != 1 {
+		return false, fmt.Errorf("min needs exactly one parameter")
+	}
+	limit, err := strconv.ParseFloat(params[0], 64)
+	if err != nil {
+		return false, fmt.Errorf("min parameter must be a number: %w", err)
+	}
+	n, err := measure(value)
+	if err != nil {
+		return false, fmt.Errorf("min rule: %w", err)
+	}
+	return n >= limit, nil
+}
+
+// MaxRule checks that the value is less than or equal to the parameter.
+// String length is checked for strings and number of items for slices, arrays and maps
+func MaxRule(value reflect.Value, params []string) (bool, error) {
+	if len(params) != 1 {
+		return false, fmt.Errorf("max needs exactly one parameter")
+	}
+	limit, err := strconv.ParseFloat(params[0], 64)
+	if err != nil {
+		return false, fmt.Errorf("max parameter must be a number: %w", err)
+	}
+	n, err := measure(value)
+	if err != nil {
+		return false, fmt.Errorf("max rule: %w", err)
+	}
+	return n <= limit, nil
+}
+
+// RangeRule checks that the value is within the inclusive range given by two parameters
+func RangeRule(value reflect.Value, params []string) (bool, error) {
+	if len(params) != 2 {
+		return false, fmt.Errorf("range needs exactly two parameters")
+	}
+	ok, err := MinRule(value, params[:1])
+	if err != nil || !ok {
+		return ok, err
+	}
+	return MaxRule(value, params[1:])
+}
+
+// OneOfRule checks that the value is one of the parameters.
+// Each item is checked in case of slices and arrays
+func OneOfRule(value reflect.Value, params []string) (bool, error) {
+	if len(params) == 0 {
+		return false, fmt.Errorf("oneOf needs at least one parameter")
+	}
+	return eachItem(value, func(item reflect.Value) bool {
+		s := fmt.Sprint(item.Interface())
+		for _, p := range params {
+			if s == p {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+var regexpCache sync.Map
+
+// RegexpRule checks that the value matches the regular expression given as the parameter.
+// Each item is checked in case of slices and arrays
+func RegexpRule(value reflect.Value, params []string) (bool, error) {
+	if len(params) != 1 {
+		return false, fmt.Errorf("regexp needs exactly one parameter")
+	}
+	var expr *regexp.Regexp
+	if cached, ok := regexpCache.Load(params[0]); ok {
+		expr = cached.(*regexp.Regexp)
+	} else {
+		compiled, err := regexp.Compile(params[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid regexp pattern: %w", err)
+		}
+		regexpCache.Store(params[0], compiled)
+		expr = compiled
+	}
+	return eachItem(value, func(item reflect.Value) bool {
+		return expr.MatchString(fmt.Sprint(item.Interface()))
+	}), nil
+}
+
+// measure returns a number to be compared by min/max rules
+func measure(value reflect.Value) (float64, error) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	default:
+		return 0, fmt.Errorf("type %s is not supported", value.Type())
+	}
+}
+
+func eachItem(value reflect.Value, check func(item reflect.Value) bool) bool {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return check(value)
+	}
+	for i := 0; i < value.Len(); i++ {
+		if !check(reflect.Indirect(value.Index(i))) {
+			return false
+		}
+	}
+	return true
+}
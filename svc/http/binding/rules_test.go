@@ -0,0 +1,99 @@
+package binding_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/velmie/x/svc/http/binding"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected []Directive
+		wantErr  bool
+	}{
+		{
+			name: "Rules with and without parameters",
+			tag:  "required; min(1) ;oneOf(a,b)",
+			expected: []Directive{
+				{Name: "required"},
+				{Name: "min", Params: []string{"1"}},
+				{Name: "oneOf", Params: []string{"a", "b"}},
+			},
+		},
+		{
+			name:     "Escaped comma",
+			tag:      `regexp(^[a-z]{1\,3}$)`,
+			expected: []Directive{{Name: "regexp", Params: []string{"^[a-z]{1,3}$"}}},
+		},
+		{
+			name:    "Unclosed parentheses",
+			tag:     "min(1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseDirectives(tt.tag)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %+v, got %+v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     RuleFunc
+		value    any
+		params   []string
+		expected bool
+	}{
+		{name: "min string length", rule: MinRule, value: "абв", params: []string{"3"}, expected: true},
+		{name: "min number", rule: MinRule, value: 2, params: []string{"3"}, expected: false},
+		{name: "max slice length", rule: MaxRule, value: []int{1, 2}, params: []string{"1"}, expected: false},
+		{name: "max float", rule: MaxRule, value: 1.5, params: []string{"1.5"}, expected: true},
+		{name: "range", rule: RangeRule, value: uint(5), params: []string{"1", "10"}, expected: true},
+		{name: "oneOf number", rule: OneOfRule, value: 2, params: []string{"1", "2"}, expected: true},
+		{name: "oneOf slice", rule: OneOfRule, value: []string{"a", "d"}, params: []string{"a", "b"}, expected: false},
+		{name: "regexp", rule: RegexpRule, value: "abc", params: []string{"^a"}, expected: true},
+		{name: "notEmpty", rule: NotEmptyRule, value: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := tt.rule(reflect.ValueOf(tt.value), tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRules_InvalidParams(t *testing.T) {
+	if _, err := MinRule(reflect.ValueOf(1), []string{"x"}); err == nil {
+		t.Error("expected error for non-numeric parameter")
+	}
+	if _, err := RegexpRule(reflect.ValueOf("a"), []string{"("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if _, err := MinRule(reflect.ValueOf(struct{}{}), []string{"1"}); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
@@ -0,0 +1,196 @@
+package errcatalog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/velmie/x/svc/http/response"
+)
+
+// Messages maps error codes to messages
+type Messages map[string]Message
+
+// Message defines the error title template and optional per-source overrides.
+// Templates use the text/template syntax, HTTPError.Meta values are available by their keys
+// and the error source is available as {{.source}}, e.g. "must be at least {{.min}} characters long"
+type Message struct {
+	// Title is used when there is no matching source override
+	Title string `json:"title" yaml:"title"`
+	// Sources maps HTTPError.Source (JSON pointer or parameter name) to the title template
+	Sources map[string]string `json:"sources" yaml:"sources"`
+}
+
+// Option configures Catalog
+type Option func(c *Catalog)
+
+// WithFallback defines the locales which are tried, in the given order,
+// when the message is not found for the locale.
+// Parent locales (e.g. "pt" for "pt-BR") and the default locale are tried anyway
+func WithFallback(locale string, fallbacks ...string) Option {
+	return func(c *Catalog) {
+		c.fallbacks[normalizeLocale(locale)] = normalizeLocales(fallbacks)
+	}
+}
+
+// Catalog stores localised error titles keyed by the error code and optionally the error source
+type Catalog struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	fallbacks     map[string][]string
+	templates     map[string]map[string]*template.Template // locale -> key -> template
+}
+
+// NewCatalog creates a new Catalog, the default locale is the last resort of every fallback chain
+func NewCatalog(defaultLocale string, opts ...Option) *Catalog {
+	c := &Catalog{
+		defaultLocale: normalizeLocale(defaultLocale),
+		fallbacks:     make(map[string][]string),
+		templates:     make(map[string]map[string]*template.Template),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Add adds messages of the locale to the catalog, existing messages with the same keys are replaced
+func (c *Catalog) Add(locale string, messages Messages) error {
+	locale = normalizeLocale(locale)
+	parsed := make(map[string]*template.Template)
+	for code, m := range messages {
+		if m.Title != "" {
+			tmpl, err := parseTemplate(locale, code, m.Title)
+			if err != nil {
+				return err
+			}
+			parsed[code] = tmpl
+		}
+		for source, title := range m.Sources {
+			key := messageKey(code, source)
+			tmpl, err := parseTemplate(locale, key, title)
+			if err != nil {
+				return err
+			}
+			parsed[key] = tmpl
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.templates[locale] == nil {
+		c.templates[locale] = make(map[string]*template.Template, len(parsed))
+	}
+	for key, tmpl := range parsed {
+		c.templates[locale][key] = tmpl
+	}
+	return nil
+}
+
+// Locales returns the fallback chain of the locales available in the catalog
+// for the given list of preferred locales
+func (c *Catalog) Locales(preferred ...string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var (
+		chain []string
+		seen  = make(map[string]bool)
+		visit func(locale string)
+	)
+	visit = func(locale string) {
+		if locale == "" || seen[locale] {
+			return
+		}
+		seen[locale] = true
+		if _, ok := c.templates[locale]; ok {
+			chain = append(chain, locale)
+		}
+		for _, f := range c.fallbacks[locale] {
+			visit(f)
+		}
+		if i := strings.LastIndex(locale, "-"); i > 0 {
+			visit(locale[:i])
+		}
+	}
+
+	for _, locale := range preferred {
+		visit(normalizeLocale(locale))
+	}
+	visit(c.defaultLocale)
+
+	return chain
+}
+
+// Localize renders the title of the error using the first locale of the chain which has a matching message.
+// A message keyed by the code and the source takes precedence over a message keyed by the code only
+func (c *Catalog) Localize(e *response.HTTPError, preferred ...string) (string, bool) {
+	title, _, ok := c.localize(e, preferred)
+	return title, ok
+}
+
+func (c *Catalog) localize(e *response.HTTPError, preferred []string) (title, locale string, ok bool) {
+	locales := c.Locales(preferred...)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data := templateData(e)
+	for _, locale := range locales {
+		keys := []string{e.Code}
+		if e.Source != "" {
+			keys = []string{messageKey(e.Code, e.Source), e.Code}
+		}
+		for _, key := range keys {
+			tmpl, ok := c.templates[locale][key]
+			if !ok {
+				continue
+			}
+			var buf bytes.Buffer
+			// a template referencing absent metadata falls through to the next candidate
+			if err := tmpl.Execute(&buf, data); err != nil {
+				continue
+			}
+			return buf.String(), locale, true
+		}
+	}
+
+	return "", "", false
+}
+
+// Title implements response.Titler, the locale is negotiated using the Accept-Language request header
+func (c *Catalog) Title(r *http.Request, e *response.HTTPError) (string, bool) {
+	title, _, ok := c.LocalizedTitle(r, e)
+	return title, ok
+}
+
+// LocalizedTitle implements response.LocalizedTitler, so ErrorWriter sets the Content-Language header
+func (c *Catalog) LocalizedTitle(r *http.Request, e *response.HTTPError) (title, locale string, ok bool) {
+	return c.localize(e, AcceptLanguage(r.Header.Get("Accept-Language")))
+}
+
+func templateData(e *response.HTTPError) map[string]any {
+	data := make(map[string]any, len(e.Meta)+1)
+	for k, v := range e.Meta {
+		data[k] = v
+	}
+	if _, ok := data["source"]; !ok {
+		data["source"] = e.Source
+	}
+	return data
+}
+
+func parseTemplate(locale, key, text string) (*template.Template, error) {
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("errcatalog: invalid message %q for locale %q: %w", key, locale, err)
+	}
+	return tmpl, nil
+}
+
+func messageKey(code, source string) string {
+	return code + "\x00" + source
+}
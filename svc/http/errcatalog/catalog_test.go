@@ -0,0 +1,186 @@
+package errcatalog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/velmie/x/svc/http/errcatalog"
+	"github.com/velmie/x/svc/http/response"
+)
+
+var testFS = fstest.MapFS{
+	"messages/en.yaml": {Data: []byte(`
+NOT_FOUND: Resource not found
+INVALID_REQUEST_PARAMETER:
+  title: "{{if eq .rule \"min\"}}Must be at least {{.min}} characters long{{else}}Invalid value{{end}}"
+  sources:
+    /email: Email address is invalid
+REQUIRED_REQUEST_PARAMETER: "{{.source}} is required"
+`)},
+	"messages/de.json": {Data: []byte(`{
+  "NOT_FOUND": "Ressource nicht gefunden",
+  "INVALID_REQUEST_PARAMETER": {"sources": {"/email": "E-Mail-Adresse ist ungültig"}}
+}`)},
+	"messages/pt.yml": {Data: []byte(`
+NOT_FOUND: Recurso não encontrado
+`)},
+	"messages/uk.yaml": {Data: []byte(`
+FORBIDDEN: Заборонено
+`)},
+	"messages/ru.yaml": {Data: []byte(`
+NOT_FOUND: Ресурс не найден
+`)},
+}
+
+func newTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c := NewCatalog("en", WithFallback("uk", "ru"))
+	if err := c.LoadFS(testFS, "messages/*"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return c
+}
+
+func TestCatalog_Localize(t *testing.T) {
+	c := newTestCatalog(t)
+
+	tests := []struct {
+		name      string
+		err       *response.HTTPError
+		preferred []string
+		expected  string
+		found     bool
+	}{
+		{
+			name:      "Exact locale",
+			err:       &response.HTTPError{Code: response.ErrCodeNotFound},
+			preferred: []string{"de"},
+			expected:  "Ressource nicht gefunden",
+			found:     true,
+		},
+		{
+			name:      "Parent locale",
+			err:       &response.HTTPError{Code: response.ErrCodeNotFound},
+			preferred: []string{"pt_BR"},
+			expected:  "Recurso não encontrado",
+			found:     true,
+		},
+		{
+			name:      "Explicit fallback",
+			err:       &response.HTTPError{Code: response.ErrCodeNotFound},
+			preferred: []string{"uk-UA"},
+			expected:  "Ресурс не найден",
+			found:     true,
+		},
+		{
+			name:      "Default locale",
+			err:       &response.HTTPError{Code: response.ErrCodeNotFound},
+			preferred: []string{"fr"},
+			expected:  "Resource not found",
+			found:     true,
+		},
+		{
+			name:      "Source specific message",
+			err:       &response.HTTPError{Code: response.ErrCodeInvalidRequestParameter, Source: "/email"},
+			preferred: []string{"de"},
+			expected:  "E-Mail-Adresse ist ungültig",
+			found:     true,
+		},
+		{
+			name: "Code message is used when locale has no source specific message",
+			err: &response.HTTPError{
+				Code:   response.ErrCodeInvalidRequestParameter,
+				Source: "/name",
+				Meta:   map[string]any{"rule": "min", "min": 3},
+			},
+			preferred: []string{"de"},
+			expected:  "Must be at least 3 characters long",
+			found:     true,
+		},
+		{
+			name:     "Source is available in template",
+			err:      &response.HTTPError{Code: response.ErrCodeRequiredRequestParameter, Source: "page"},
+			expected: "page is required",
+			found:    true,
+		},
+		{
+			name: "Template referencing absent meta is skipped",
+			err:  &response.HTTPError{Code: response.ErrCodeInvalidRequestParameter, Source: "/name"},
+		},
+		{
+			name:      "Unknown code",
+			err:       &response.HTTPError{Code: response.ErrCodeExhausted},
+			preferred: []string{"de"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, found := c.Localize(tt.err, tt.preferred...)
+			if found != tt.found || actual != tt.expected {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.found, actual, found)
+			}
+		})
+	}
+}
+
+func TestCatalog_Locales(t *testing.T) {
+	c := newTestCatalog(t)
+
+	actual := c.Locales("uk-UA", "pt-BR", "de")
+	expected := []string{"uk", "ru", "pt", "de", "en"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCatalog_WithErrorWriter(t *testing.T) {
+	w := response.NewErrorWriter(response.WithTitler(newTestCatalog(t)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept-Language", "fr-CH, de;q=0.9, en;q=0.8")
+	rec := httptest.NewRecorder()
+
+	err := w.Write(rec, req, &response.HTTPError{
+		Code:       response.ErrCodeNotFound,
+		Target:     response.TargetCommon,
+		StatusCode: http.StatusNotFound,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"errors":[{"code":"NOT_FOUND","title":"Ressource nicht gefunden","target":"common"}]}`
+	if actual := strings.TrimSpace(rec.Body.String()); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+	if actual := rec.Header().Get("Content-Language"); actual != "de" {
+		t.Errorf("expected Content-Language de, got %q", actual)
+	}
+	if actual := rec.Header().Get("Vary"); actual != "Accept-Language" {
+		t.Errorf("expected Vary Accept-Language, got %q", actual)
+	}
+}
+
+func TestCatalog_LoadFS_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		fs   fstest.MapFS
+	}{
+		{name: "Unsupported format", fs: fstest.MapFS{"en.txt": {Data: []byte("NOT_FOUND: x")}}},
+		{name: "Malformed file", fs: fstest.MapFS{"en.json": {Data: []byte("{")}}},
+		{name: "Invalid template", fs: fstest.MapFS{"en.yaml": {Data: []byte("NOT_FOUND: '{{.x'")}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewCatalog("en").LoadFS(tt.fs, "*"); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
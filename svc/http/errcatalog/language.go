@@ -0,0 +1,66 @@
+package errcatalog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptLanguage parses the Accept-Language header value and returns the language tags
+// ordered by their quality values. Wildcards and tags with zero quality are omitted
+func AcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(name) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				q = 0
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		tags = append(tags, weighted{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, t.tag)
+	}
+	return result
+}
+
+// normalizeLocale brings the locale to the lower case hyphen separated form, e.g. "pt_BR" becomes "pt-br"
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+func normalizeLocales(locales []string) []string {
+	result := make([]string, 0, len(locales))
+	for _, l := range locales {
+		result = append(result, normalizeLocale(l))
+	}
+	return result
+}
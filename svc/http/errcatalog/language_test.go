@@ -0,0 +1,30 @@
+package errcatalog_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/velmie/x/svc/http/errcatalog"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []string
+	}{
+		{header: "", expected: []string{}},
+		{header: "en-US", expected: []string{"en-US"}},
+		{header: "fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", expected: []string{"fr-CH", "fr", "en", "de"}},
+		{header: "en;q=0.5, de, ru;q=0", expected: []string{"de", "en"}},
+		{header: "en;q=abc, de", expected: []string{"de"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			actual := AcceptLanguage(tt.header)
+			if !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package errcatalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalJSON allows to define a message as a plain string which is the title
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var title string
+	if err := json.Unmarshal(data, &title); err == nil {
+		*m = Message{Title: title}
+		return nil
+	}
+	type message Message
+	return json.Unmarshal(data, (*message)(m))
+}
+
+// UnmarshalYAML allows to define a message as a plain string which is the title
+func (m *Message) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*m = Message{Title: node.Value}
+		return nil
+	}
+	type message Message
+	return node.Decode((*message)(m))
+}
+
+// LoadFS adds messages from the files of the file system (e.g. embed.FS) matching the given patterns.
+// The locale is taken from the file name without extension, e.g. "en-US.yaml".
+// Files with .json, .yaml and .yml extensions are supported
+//
+// File example:
+//
+//	NOT_FOUND: The requested resource was not found
+//	INVALID_REQUEST_PARAMETER:
+//	  title: The value is invalid
+//	  sources:
+//	    /email: The email address is invalid
+func (c *Catalog) LoadFS(fsys fs.FS, patterns ...string) error {
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return fmt.Errorf("errcatalog: invalid pattern %q: %w", pattern, err)
+		}
+		for _, file := range files {
+			if err = c.loadFile(fsys, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) loadFile(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return fmt.Errorf("errcatalog: cannot read %s: %w", file, err)
+	}
+
+	ext := path.Ext(file)
+	locale := strings.TrimSuffix(path.Base(file), ext)
+
+	var messages Messages
+	switch strings.ToLower(ext) {
+	case ".json":
+		err = json.Unmarshal(data, &messages)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &messages)
+	default:
+		return fmt.Errorf("errcatalog: unsupported file format %s", file)
+	}
+	if err != nil {
+		return fmt.Errorf("errcatalog: cannot decode %s: %w", file, err)
+	}
+
+	return c.Add(locale, messages)
+}
@@ -2,4 +2,7 @@ module github.com/velmie/x/svc/http
 
 go 1.21
 
-require go.uber.org/mock v0.2.0
+require (
+	go.uber.org/mock v0.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
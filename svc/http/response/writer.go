@@ -0,0 +1,138 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Titler resolves a human-readable title for the error in the context of the given request
+type Titler interface {
+	Title(r *http.Request, e *HTTPError) (title string, ok bool)
+}
+
+// TitlerFunc is an adapter to allow the use of ordinary functions as Titler
+type TitlerFunc func(r *http.Request, e *HTTPError) (string, bool)
+
+// Title calls f(r, e)
+func (f TitlerFunc) Title(r *http.Request, e *HTTPError) (string, bool) {
+	return f(r, e)
+}
+
+// LocalizedTitler is Titler which resolves the title from the Accept-Language request header
+// and reports the locale of the title
+type LocalizedTitler interface {
+	Titler
+	LocalizedTitle(r *http.Request, e *HTTPError) (title, locale string, ok bool)
+}
+
+// ErrorWriterOption configures ErrorWriter
+type ErrorWriterOption func(w *ErrorWriter)
+
+// WithTitler sets Titler which is used in order to fill empty error titles
+func WithTitler(t Titler) ErrorWriterOption {
+	return func(w *ErrorWriter) {
+		w.titler = t
+	}
+}
+
+// WithDefaultStatusCode sets the status code used when none of the errors carries it.
+// By default, http.StatusInternalServerError is used
+func WithDefaultStatusCode(code int) ErrorWriterOption {
+	return func(w *ErrorWriter) {
+		w.defaultStatusCode = code
+	}
+}
+
+// ErrorWriter writes errors response payload
+type ErrorWriter struct {
+	titler            Titler
+	defaultStatusCode int
+}
+
+// NewErrorWriter creates a new ErrorWriter
+func NewErrorWriter(opts ...ErrorWriterOption) *ErrorWriter {
+	w := &ErrorWriter{defaultStatusCode: http.StatusInternalServerError}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Write writes the errors as JSON payload.
+// The status code is taken from the first error carrying it.
+// Empty titles are filled by Titler if it is configured, the given errors are not modified.
+// Vary and Content-Language headers are set if the title is localized by LocalizedTitler
+func (ew *ErrorWriter) Write(w http.ResponseWriter, r *http.Request, errs ...*HTTPError) error {
+	statusCode := ew.defaultStatusCode
+	for _, e := range errs {
+		if e.StatusCode != 0 {
+			statusCode = e.StatusCode
+			break
+		}
+	}
+
+	var locales []string
+	localized, isLocalized := ew.titler.(LocalizedTitler)
+	if ew.titler != nil {
+		titled := make([]*HTTPError, len(errs))
+		for i, e := range errs {
+			titled[i] = e
+			if e.Title != "" {
+				continue
+			}
+
+			var (
+				title, locale string
+				ok            bool
+			)
+			if isLocalized {
+				title, locale, ok = localized.LocalizedTitle(r, e)
+			} else {
+				title, ok = ew.titler.Title(r, e)
+			}
+			if !ok {
+				continue
+			}
+			c := *e
+			c.Title = title
+			titled[i] = &c
+			if locale != "" && !contains(locales, locale) {
+				locales = append(locales, locale)
+			}
+		}
+		errs = titled
+	}
+
+	if isLocalized {
+		// caches must not serve the body to clients with another Accept-Language
+		addVary(w.Header(), "Accept-Language")
+	}
+	if len(locales) > 0 {
+		w.Header().Set("Content-Language", strings.Join(locales, ", "))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(Error(errs...))
+}
+
+// addVary adds the header name to the Vary header unless it is already listed
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v == "*" || strings.EqualFold(v, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package response_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/velmie/x/svc/http/response"
+)
+
+func TestErrorWriter_Write(t *testing.T) {
+	titler := TitlerFunc(func(r *http.Request, e *HTTPError) (string, bool) {
+		if e.Code == ErrCodeNotFound {
+			return "not found in " + r.URL.Path, true
+		}
+		return "", false
+	})
+
+	tests := []struct {
+		name           string
+		opts           []ErrorWriterOption
+		errs           []*HTTPError
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Title is filled by titler",
+			opts: []ErrorWriterOption{WithTitler(titler)},
+			errs: []*HTTPError{
+				{Code: ErrCodeNotFound, Target: TargetCommon, StatusCode: http.StatusNotFound},
+				{Code: ErrCodeForbidden, Target: TargetCommon},
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"errors":[{"code":"NOT_FOUND","title":"not found in /items","target":"common"},{"code":"FORBIDDEN","target":"common"}]}`,
+		},
+		{
+			name: "Existing title is kept",
+			opts: []ErrorWriterOption{WithTitler(titler)},
+			errs: []*HTTPError{
+				{Code: ErrCodeNotFound, Title: "custom", Target: TargetCommon},
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"errors":[{"code":"NOT_FOUND","title":"custom","target":"common"}]}`,
+		},
+		{
+			name:           "Default status code",
+			opts:           []ErrorWriterOption{WithDefaultStatusCode(http.StatusBadRequest)},
+			errs:           []*HTTPError{{Code: ErrCodeNotFound, Target: TargetCommon}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"errors":[{"code":"NOT_FOUND","target":"common"}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/items", http.NoBody)
+
+			if err := NewErrorWriter(tc.opts...).Write(rec, req, tc.errs...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if rec.Code != tc.expectedStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("unexpected content type %q", ct)
+			}
+			if actual := strings.TrimSpace(rec.Body.String()); actual != tc.expectedBody {
+				t.Errorf("expected %s, got %s", tc.expectedBody, actual)
+			}
+			for _, e := range tc.errs {
+				if e.Code == ErrCodeNotFound && e.Title == "not found in /items" {
+					t.Error("given errors must not be modified")
+				}
+			}
+			if vary := rec.Header().Get("Vary"); vary != "" {
+				t.Errorf("unexpected Vary header %q", vary)
+			}
+			if lang := rec.Header().Get("Content-Language"); lang != "" {
+				t.Errorf("unexpected Content-Language header %q", lang)
+			}
+		})
+	}
+}
+
+type localizedTitler struct{}
+
+func (localizedTitler) Title(*http.Request, *HTTPError) (string, bool) {
+	return "", false
+}
+
+func (localizedTitler) LocalizedTitle(r *http.Request, e *HTTPError) (string, string, bool) {
+	switch e.Code {
+	case ErrCodeNotFound:
+		return "nicht gefunden", "de", true
+	case ErrCodeForbidden:
+		return "not allowed", "en", true
+	default:
+		return "", "", false
+	}
+}
+
+func TestErrorWriter_Write_LocalizedTitle(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "Origin")
+	req := httptest.NewRequest(http.MethodGet, "/items", http.NoBody)
+
+	err := NewErrorWriter(WithTitler(localizedTitler{})).Write(rec, req,
+		&HTTPError{Code: ErrCodeNotFound, Target: TargetCommon},
+		&HTTPError{Code: ErrCodeForbidden, Target: TargetCommon},
+		&HTTPError{Code: ErrCodeNotFound, Target: TargetCommon},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if lang := rec.Header().Get("Content-Language"); lang != "de, en" {
+		t.Errorf("expected Content-Language %q, got %q", "de, en", lang)
+	}
+	if vary := rec.Header().Values("Vary"); len(vary) != 2 || vary[1] != "Accept-Language" {
+		t.Errorf("expected Vary [Origin Accept-Language], got %v", vary)
+	}
+}
@@ -0,0 +1,7 @@
+module github.com/velmie/x/svc/logx
+
+go 1.21.0
+
+require go.opentelemetry.io/otel/trace v1.20.0
+
+require go.opentelemetry.io/otel v1.20.0 // indirect
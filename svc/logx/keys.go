@@ -0,0 +1,26 @@
+package logx
+
+import "log/slog"
+
+// Keys used by the packages of the repository, so logs of different packages are queried the same way
+const (
+	// KeyError holds the error message
+	KeyError = "error"
+	// KeyComponent holds the name of the package or the subsystem which has produced the record, e.g. "sqltx"
+	KeyComponent = "component"
+	// KeyService holds the name of the bootstrap service
+	KeyService = "service"
+	// KeyTraceID holds the trace id of the span found in the context, the name follows the OpenTelemetry log data model
+	KeyTraceID = "trace_id"
+	// KeySpanID holds the span id of the span found in the context
+	KeySpanID = "span_id"
+)
+
+// Err returns the attribute holding the error message under KeyError. Nil error results in an empty attribute
+// which is ignored by handlers
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.String(KeyError, err.Error())
+}
@@ -0,0 +1,133 @@
+// Package logx adapts log/slog to the Logger interfaces of the repository packages
+package logx
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Logger implements the Logger interfaces of bootstrap, sqltx, envx, authx and sqlconnection/mysql packages
+// on top of slog.Handler. Trace and span ids are added to records when the context holds a span
+type Logger struct {
+	handler slog.Handler
+	ctx     context.Context
+}
+
+// New creates Logger writing records to the handler. The handler is wrapped with TraceHandler
+func New(h slog.Handler) *Logger {
+	return &Logger{
+		handler: NewTraceHandler(h),
+		ctx:     context.Background(),
+	}
+}
+
+// FromSlog creates Logger using the handler of the slog logger
+func FromSlog(l *slog.Logger) *Logger {
+	return New(l.Handler())
+}
+
+// Default creates Logger using the handler of slog.Default
+func Default() *Logger {
+	return FromSlog(slog.Default())
+}
+
+// Debug logs at slog.LevelDebug using the context bound by WithContext
+func (l *Logger) Debug(msg string, args ...any) {
+	l.log(l.ctx, slog.LevelDebug, msg, args)
+}
+
+// Info logs at slog.LevelInfo using the context bound by WithContext
+func (l *Logger) Info(msg string, args ...any) {
+	l.log(l.ctx, slog.LevelInfo, msg, args)
+}
+
+// Warn logs at slog.LevelWarn using the context bound by WithContext
+func (l *Logger) Warn(msg string, args ...any) {
+	l.log(l.ctx, slog.LevelWarn, msg, args)
+}
+
+// Error logs at slog.LevelError using the context bound by WithContext
+func (l *Logger) Error(msg string, args ...any) {
+	l.log(l.ctx, slog.LevelError, msg, args)
+}
+
+// DebugContext logs at slog.LevelDebug with the given context
+func (l *Logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelDebug, msg, args)
+}
+
+// InfoContext logs at slog.LevelInfo with the given context
+func (l *Logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelInfo, msg, args)
+}
+
+// WarnContext logs at slog.LevelWarn with the given context
+func (l *Logger) WarnContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelWarn, msg, args)
+}
+
+// ErrorContext logs at slog.LevelError with the given context
+func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelError, msg, args)
+}
+
+// With returns Logger which adds the given key/value pairs or attributes to every record
+func (l *Logger) With(args ...any) *Logger {
+	if len(args) == 0 {
+		return l
+	}
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return &Logger{handler: l.handler.WithAttrs(attrs), ctx: l.ctx}
+}
+
+// WithGroup returns Logger which puts attributes of records into the group
+func (l *Logger) WithGroup(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	return &Logger{handler: l.handler.WithGroup(name), ctx: l.ctx}
+}
+
+// WithContext returns Logger which passes the context to the handler when logging without context,
+// e.g. in order to correlate logs of a component with the request trace
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return &Logger{handler: l.handler, ctx: ctx}
+}
+
+// Component returns Logger which adds the component name under KeyComponent to every record
+func (l *Logger) Component(name string) *Logger {
+	return l.With(KeyComponent, name)
+}
+
+// Slog returns slog.Logger sharing the handler of the Logger
+func (l *Logger) Slog() *slog.Logger {
+	return slog.New(l.handler)
+}
+
+// Handler returns the handler of the Logger
+func (l *Logger) Handler() slog.Handler {
+	return l.handler
+}
+
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, args []any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// skip runtime.Callers, log and the exported method
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.handler.Handle(ctx, r)
+}
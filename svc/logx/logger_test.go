@@ -0,0 +1,156 @@
+package logx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/velmie/x/svc/logx"
+)
+
+// interfaces of the repository packages
+var (
+	_ interface {
+		Info(msg string, args ...any)
+		Error(msg string, args ...any)
+	} = (*logx.Logger)(nil) // bootstrap
+	_ interface {
+		Warn(msg string, args ...any)
+	} = (*logx.Logger)(nil) // sqltx, envx
+	_ interface {
+		Info(msg string, v ...any)
+		Warn(msg string, v ...any)
+		Error(msg string, v ...any)
+		Debug(msg string, v ...any)
+	} = (*logx.Logger)(nil) // authx
+)
+
+func newLogger(opts *slog.HandlerOptions) (*logx.Logger, *bytes.Buffer) {
+	buf := new(bytes.Buffer)
+	return logx.New(slog.NewJSONHandler(buf, opts)), buf
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var rec map[string]any
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("cannot decode record: %s", err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func spanContext() trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:     trace.SpanID{0x04, 0x05},
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func TestLogger_Levels(t *testing.T) {
+	logger, buf := newLogger(&slog.HandlerOptions{Level: slog.LevelInfo})
+
+	logger.Debug("debug")
+	logger.Info("info", "maxConn", 10)
+	logger.Warn("warn")
+	logger.Error("error", logx.Err(errors.New("failure")))
+
+	records := decode(t, buf)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records since debug is disabled, got %d", len(records))
+	}
+	for i, level := range []string{"INFO", "WARN", "ERROR"} {
+		if records[i]["level"] != level {
+			t.Errorf("record %d: expected level %s, got %v", i, level, records[i]["level"])
+		}
+	}
+	if records[0]["maxConn"] != float64(10) {
+		t.Errorf("expected maxConn attribute, got %v", records[0])
+	}
+	if records[2][logx.KeyError] != "failure" {
+		t.Errorf("expected error attribute, got %v", records[2])
+	}
+}
+
+func TestLogger_Trace(t *testing.T) {
+	logger, buf := newLogger(nil)
+	sc := spanContext()
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	logger.InfoContext(ctx, "with context")
+	logger.WithContext(ctx).Warn("bound context")
+	logger.Info("without context")
+
+	records := decode(t, buf)
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	for _, rec := range records[:2] {
+		if rec[logx.KeyTraceID] != sc.TraceID().String() || rec[logx.KeySpanID] != sc.SpanID().String() {
+			t.Errorf("expected trace attributes, got %v", rec)
+		}
+	}
+	if _, ok := records[2][logx.KeyTraceID]; ok {
+		t.Errorf("unexpected trace attributes, got %v", records[2])
+	}
+}
+
+func TestLogger_With(t *testing.T) {
+	logger, buf := newLogger(nil)
+
+	logger.Component("sqltx").With(slog.String("db", "main")).WithGroup("query").Info("slow", "ms", 120)
+
+	records := decode(t, buf)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	rec := records[0]
+	if rec[logx.KeyComponent] != "sqltx" || rec["db"] != "main" {
+		t.Errorf("expected component and db attributes, got %v", rec)
+	}
+	group, ok := rec["query"].(map[string]any)
+	if !ok || group["ms"] != float64(120) {
+		t.Errorf("expected grouped attribute, got %v", rec)
+	}
+}
+
+func TestLogger_Source(t *testing.T) {
+	logger, buf := newLogger(&slog.HandlerOptions{AddSource: true})
+
+	logger.Info("source")
+
+	records := decode(t, buf)
+	source, _ := records[0]["source"].(map[string]any)
+	if file, _ := source["file"].(string); filepath.Base(file) != "logger_test.go" {
+		t.Errorf("expected the caller to be reported as source, got %v", source)
+	}
+}
+
+func TestLogger_Slog(t *testing.T) {
+	logger, buf := newLogger(nil)
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContext())
+
+	logger.Slog().InfoContext(ctx, "slog")
+
+	records := decode(t, buf)
+	if records[0][logx.KeyTraceID] == nil {
+		t.Errorf("expected trace attributes, got %v", records[0])
+	}
+}
+
+func TestErr(t *testing.T) {
+	if a := logx.Err(nil); !a.Equal(slog.Attr{}) {
+		t.Errorf("expected empty attribute, got %v", a)
+	}
+}
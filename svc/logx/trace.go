@@ -0,0 +1,55 @@
+package logx
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHandler adds the trace id and the span id of the span found in the context to every record.
+// Use context-aware logging methods, e.g. Logger.InfoContext or Logger.WithContext, in order to pass the context
+type TraceHandler struct {
+	handler slog.Handler
+}
+
+// NewTraceHandler wraps the handler with TraceHandler
+func NewTraceHandler(h slog.Handler) *TraceHandler {
+	if th, ok := h.(*TraceHandler); ok {
+		return th
+	}
+	return &TraceHandler{handler: h}
+}
+
+// Enabled reports whether the wrapped handler handles records at the given level
+func (h *TraceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle adds trace attributes to the record and passes it to the wrapped handler.
+// Note that the attributes are added to the current group if the handler is created by WithGroup
+func (h *TraceHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r = r.Clone()
+		r.AddAttrs(
+			slog.String(KeyTraceID, sc.TraceID().String()),
+			slog.String(KeySpanID, sc.SpanID().String()),
+		)
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs returns TraceHandler wrapping the handler with the given attributes
+func (h *TraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &TraceHandler{handler: h.handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns TraceHandler wrapping the handler with the given group
+func (h *TraceHandler) WithGroup(name string) slog.Handler {
+	return &TraceHandler{handler: h.handler.WithGroup(name)}
+}
+
+// Unwrap returns the wrapped handler
+func (h *TraceHandler) Unwrap() slog.Handler {
+	return h.handler
+}
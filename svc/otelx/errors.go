@@ -0,0 +1,98 @@
+package otelx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorCodeKey is the attribute key of the machine readable error code
+const ErrorCodeKey = attribute.Key("error.code")
+
+// RecordError records the error on the span from the context as an exception event and sets the span status to error.
+// Details provided by the error chain are recorded as event attributes, errorsx.DetailedError provides them all:
+//   - ErrorCode() string - the error code, recorded as "error.code";
+//   - ErrorAttrs() []slog.Attr - attributes, groups are flattened using dots;
+//   - StackTrace() string - the stack trace, recorded as "exception.stacktrace".
+func RecordError(ctx context.Context, err error, opts ...trace.EventOption) {
+	if err == nil {
+		return
+	}
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	opts = append(opts, trace.WithAttributes(ErrorAttributes(err)...))
+	span.RecordError(err, opts...)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// ErrorAttributes collects attributes provided by the error chain
+func ErrorAttributes(err error) []attribute.KeyValue {
+	var (
+		attrs []attribute.KeyValue
+		code  string
+		stack string
+	)
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if p, ok := e.(interface{ ErrorCode() string }); ok && code == "" {
+			code = p.ErrorCode()
+		}
+		if p, ok := e.(interface{ ErrorAttrs() []slog.Attr }); ok {
+			for _, a := range p.ErrorAttrs() {
+				attrs = appendSlogAttr(attrs, "", a)
+			}
+		}
+		// the innermost stack trace is the closest to the origin of the error
+		if p, ok := e.(interface{ StackTrace() string }); ok {
+			if s := p.StackTrace(); s != "" {
+				stack = s
+			}
+		}
+	}
+	if code != "" {
+		attrs = append(attrs, ErrorCodeKey.String(code))
+	}
+	if stack != "" {
+		attrs = append(attrs, semconv.ExceptionStacktrace(stack))
+	}
+	return attrs
+}
+
+func appendSlogAttr(attrs []attribute.KeyValue, prefix string, a slog.Attr) []attribute.KeyValue {
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return append(attrs, attribute.String(key, v.String()))
+	case slog.KindInt64:
+		return append(attrs, attribute.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(attrs, attribute.Int64(key, int64(v.Uint64())))
+	case slog.KindFloat64:
+		return append(attrs, attribute.Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(attrs, attribute.Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(attrs, attribute.String(key, v.Duration().String()))
+	case slog.KindTime:
+		return append(attrs, attribute.String(key, v.Time().Format(time.RFC3339Nano)))
+	case slog.KindGroup:
+		for _, ga := range v.Group() {
+			attrs = appendSlogAttr(attrs, key, ga)
+		}
+		return attrs
+	default:
+		return append(attrs, attribute.String(key, fmt.Sprint(v.Any())))
+	}
+}
@@ -0,0 +1,67 @@
+package otelx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/velmie/x/svc/otelx"
+)
+
+type detailedError struct {
+	cause error
+}
+
+func (e *detailedError) Error() string      { return e.cause.Error() }
+func (e *detailedError) Unwrap() error      { return e.cause }
+func (e *detailedError) ErrorCode() string  { return "ORDER_NOT_FOUND" }
+func (e *detailedError) StackTrace() string { return "main.findOrder\n\tmain.go:42\n" }
+func (e *detailedError) ErrorAttrs() []slog.Attr {
+	return []slog.Attr{slog.Int("orderId", 42), slog.Group("user", slog.String("id", "7"))}
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "operation")
+	RecordError(ctx, fmt.Errorf("pay: %w", &detailedError{cause: errors.New("not found")}))
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+
+	events := spans[0].Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "exception", events[0].Name)
+
+	attrs := attribute.NewSet(events[0].Attributes...)
+	for key, expected := range map[attribute.Key]attribute.Value{
+		"exception.message":    attribute.StringValue("pay: not found"),
+		"exception.stacktrace": attribute.StringValue("main.findOrder\n\tmain.go:42\n"),
+		"error.code":           attribute.StringValue("ORDER_NOT_FOUND"),
+		"orderId":              attribute.Int64Value(42),
+		"user.id":              attribute.StringValue("7"),
+	} {
+		actual, ok := attrs.Value(key)
+		assert.True(t, ok, "attribute %s is missing", key)
+		assert.Equal(t, expected, actual, "attribute %s", key)
+	}
+}
+
+func TestRecordError_NoSpan(t *testing.T) {
+	assert.NotPanics(t, func() {
+		RecordError(context.Background(), errors.New("failed"))
+		RecordError(context.Background(), nil)
+	})
+}
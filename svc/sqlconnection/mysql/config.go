@@ -20,6 +20,8 @@ type Config struct {
 	Name     string
 	User     string
 	Password string
+	// PasswordFile is re-read for each new physical connection, Password is ignored if the file is set
+	PasswordFile string
 
 	MaxOpenConnections int           // leave 0 to use default value
 	MaxIdleConnections int           // leave 0 to use default value
@@ -41,6 +43,7 @@ func ConfigFromEnv(envPrefix string) (*Config, error) {
 	const (
 		envNameUnsafeDisableTLS = "DB_UNSAFE_DISABLE_TLS"
 		envNameTLSCertPath      = "DB_TLS_CERT_PATH"
+		envNamePassFile         = "DB_PASS_FILE"
 	)
 
 	err := envx.Supply(
@@ -48,7 +51,7 @@ func ConfigFromEnv(envPrefix string) (*Config, error) {
 		envx.Set(&res.Port, p.Get("DB_PORT").Required().NotEmpty().ValidPortNumber().Int),
 		envx.Set(&res.Name, p.Get("DB_NAME").Required().NotEmpty().String),
 		envx.Set(&res.User, p.Get("DB_USER").Required().NotEmpty().String),
-		envx.Set(&res.Password, p.Get("DB_PASS").Required().NotEmpty().String),
+		envx.Set(&res.PasswordFile, p.Get(envNamePassFile).String),
 
 		envx.Set(&res.MaxOpenConnections, p.Get("DB_MAX_OPEN_CONNECTIONS").Int),
 		envx.Set(&res.MaxIdleConnections, p.Get("DB_MAX_IDLE_CONNECTIONS").Int),
@@ -61,6 +64,18 @@ func ConfigFromEnv(envPrefix string) (*Config, error) {
 		return nil, err
 	}
 
+	passwordRequired := res.PasswordFile == ""
+	err = envx.Supply(
+		envx.Set(&res.Password, p.Get("DB_PASS").RequiredIf(passwordRequired).NotEmptyIf(passwordRequired).String),
+	)
+	if err != nil {
+		err = fmt.Errorf(
+			`%w. Set "%s" if the password is read from a file`,
+			err, withPrefix(envPrefix, envNamePassFile),
+		)
+		return nil, err
+	}
+
 	err = envx.Supply(
 		envx.Set(&tlsCertPath, p.Get(envNameTLSCertPath).RequiredIf(!disableTLS).NotEmptyIf(!disableTLS).String),
 	)
@@ -22,6 +22,7 @@ func TestConfigFromEnv(t *testing.T) {
 		"DB_CONNECTION_MAX_IDLE_TIME",
 		"DB_UNSAFE_DISABLE_TLS",
 		"DB_TLS_CERT_PATH",
+		"DB_PASS_FILE",
 	}
 
 	tests := []struct {
@@ -263,6 +264,29 @@ func TestConfigFromEnv(t *testing.T) {
 			},
 			expectsError: false,
 		},
+		{
+			name: "everything is ok: password file instead of password",
+			envs: map[string]string{
+				"DB_HOST":               "localhost",
+				"DB_PORT":               "3306",
+				"DB_NAME":               "db_name",
+				"DB_USER":               "db_user",
+				"DB_PASS_FILE":          "/run/secrets/db_pass",
+				"DB_UNSAFE_DISABLE_TLS": "true",
+			},
+			expectsError: false,
+		},
+		{
+			name: "Password and password file are missed",
+			envs: map[string]string{
+				"DB_HOST":               "localhost",
+				"DB_PORT":               "3306",
+				"DB_NAME":               "db_name",
+				"DB_USER":               "db_user",
+				"DB_UNSAFE_DISABLE_TLS": "true",
+			},
+			expectsError: true,
+		},
 		{
 			name: "everything is ok: only required vars; host is a local domain name",
 			envs: map[string]string{
@@ -19,8 +19,13 @@ type Logger interface {
 	Info(msg string, args ...any)
 }
 
-// NewConnection creates new database connection
+// NewConnection creates new database connection, the password is re-read from Config.PasswordFile
+// for each new physical connection if the file is set
 func NewConnection(cfg *Config, log Logger) (*sql.DB, error) {
+	if cfg.PasswordFile != "" {
+		return NewConnectionWithCredentials(cfg, FileCredentials(cfg.User, cfg.PasswordFile), log)
+	}
+
 	dsn := fmt.Sprintf(
 		"%s:%s@tcp(%s:%d)/%s?parseTime=true",
 		cfg.User,
@@ -43,7 +48,23 @@ func NewConnection(cfg *Config, log Logger) (*sql.DB, error) {
 		return nil, fmt.Errorf("cannot open mysql connection: %w", err)
 	}
 
-	err = db.Ping()
+	return setupConnection(db, cfg, log)
+}
+
+// NewConnectionWithCredentials creates new database connection which gets credentials from the provider
+// for each new physical connection, see Connector
+func NewConnectionWithCredentials(
+	cfg *Config,
+	provider CredentialsProvider,
+	log Logger,
+	opts ...ConnectorOption,
+) (*sql.DB, error) {
+	return setupConnection(sql.OpenDB(NewConnector(cfg, provider, log, opts...)), cfg, log)
+}
+
+// setupConnection checks the connection and sets the connection pool limits
+func setupConnection(db *sql.DB, cfg *Config, log Logger) (*sql.DB, error) {
+	err := db.Ping()
 	if err != nil {
 		return nil, fmt.Errorf("mysql connection is not established: %w", err)
 	}
@@ -0,0 +1,205 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/velmie/x/envx"
+)
+
+// ErrNumAccessDenied is the MySQL server error number returned for invalid credentials
+const ErrNumAccessDenied = 1045
+
+const (
+	defaultGracePeriod = 5 * time.Minute
+)
+
+// Credentials are the database user and password
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CredentialsProvider returns the current database credentials, it is called for each new physical connection
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is an adapter to allow the use of ordinary functions as CredentialsProvider
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// FileCredentials reads the password from the file, e.g. the one mounted by the secrets manager.
+// Leading and trailing white space of the file content is ignored
+func FileCredentials(user, passwordPath string) CredentialsProvider {
+	return CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		password, err := os.ReadFile(passwordPath)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("cannot read database password: %w", err)
+		}
+		return Credentials{User: user, Password: strings.TrimSpace(string(password))}, nil
+	})
+}
+
+// SourceCredentials reads the user and the password with the given names from the envx source
+func SourceCredentials(src envx.Source, userName, passwordName string) CredentialsProvider {
+	lookup := func(name string) (string, error) {
+		value, found, err := src.Lookup(name)
+		if err != nil {
+			return "", fmt.Errorf("cannot lookup '%s' in %s: %w", name, src.Name(), err)
+		}
+		if !found || value == "" {
+			return "", fmt.Errorf("'%s' is not found in %s", name, src.Name())
+		}
+		return value, nil
+	}
+
+	return CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		user, err := lookup(userName)
+		if err != nil {
+			return Credentials{}, err
+		}
+		password, err := lookup(passwordName)
+		if err != nil {
+			return Credentials{}, err
+		}
+		return Credentials{User: user, Password: password}, nil
+	})
+}
+
+// ConnectorOption configures Connector
+type ConnectorOption func(c *Connector)
+
+// WithGracePeriod sets the period after rotation during which the previous credentials are used
+// if the server denies access with the new ones, zero disables the fallback. Default is 5m
+func WithGracePeriod(d time.Duration) ConnectorOption {
+	return func(c *Connector) {
+		c.gracePeriod = d
+	}
+}
+
+// Connector is driver.Connector which gets credentials from the provider for each new physical connection,
+// so rotated credentials are used without recreating sql.DB. Already established connections are not affected
+type Connector struct {
+	base        *mysql.Config
+	provider    CredentialsProvider
+	log         Logger
+	gracePeriod time.Duration
+
+	mu        sync.Mutex
+	current   *Credentials
+	previous  *Credentials
+	rotatedAt time.Time
+}
+
+// NewConnector creates new connector, User and Password of the config are ignored
+func NewConnector(cfg *Config, provider CredentialsProvider, log Logger, opts ...ConnectorOption) *Connector {
+	base := mysql.NewConfig()
+	base.Net = "tcp"
+	base.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	base.DBName = cfg.Name
+	base.ParseTime = true
+	base.TLS = cfg.TLSConfig
+
+	c := &Connector{
+		base:        base,
+		provider:    provider,
+		log:         log,
+		gracePeriod: defaultGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Connect establishes the connection with the current credentials
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.connect(ctx, creds)
+	if err == nil || !isAccessDenied(err) {
+		return conn, err
+	}
+
+	previous, ok := c.previousCredentials()
+	if !ok {
+		return nil, err
+	}
+	conn, prevErr := c.connect(ctx, previous)
+	if prevErr != nil {
+		return nil, err
+	}
+	c.log.Info("DB connection is established with the previous credentials", "user", previous.User)
+
+	return conn, nil
+}
+
+// Driver returns the MySQL driver
+func (c *Connector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
+
+// credentials gets credentials from the provider and remembers the previous ones on rotation
+func (c *Connector) credentials(ctx context.Context) (Credentials, error) {
+	creds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("cannot get database credentials: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil && *c.current != creds {
+		c.previous = c.current
+		c.rotatedAt = time.Now()
+		c.log.Info("database credentials are rotated", "user", creds.User)
+	}
+	c.current = &creds
+
+	return creds, nil
+}
+
+func (c *Connector) previousCredentials() (Credentials, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.previous == nil || time.Since(c.rotatedAt) >= c.gracePeriod {
+		return Credentials{}, false
+	}
+	return *c.previous, true
+}
+
+func (c *Connector) connect(ctx context.Context, creds Credentials) (driver.Conn, error) {
+	cfg := c.base.Clone()
+	cfg.User = creds.User
+	cfg.Passwd = creds.Password
+
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mysql connector: %w", err)
+	}
+	return connector.Connect(ctx)
+}
+
+func isAccessDenied(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == ErrNumAccessDenied
+}
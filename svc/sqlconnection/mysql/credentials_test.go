@@ -0,0 +1,339 @@
+package mysql_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	drivermysql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/envx"
+	"github.com/velmie/x/svc/sqlconnection/mysql"
+)
+
+func TestConnector_Rotation(t *testing.T) {
+	srv := newFakeServer(t, "old")
+	password := &syncValue{value: "old"}
+	log := &recordingLogger{}
+
+	db := sql.OpenDB(mysql.NewConnector(srv.config(), password.provider("app"), log))
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+
+	require.NoError(t, db.Ping())
+
+	srv.accept("new")
+	password.set("new")
+	require.NoError(t, db.Ping())
+	require.NoError(t, db.Ping())
+
+	require.Equal(t, []string{"old", "new", "new"}, srv.logins())
+	require.Equal(t, []string{"database credentials are rotated"}, log.messages())
+}
+
+func TestConnector_GracePeriod(t *testing.T) {
+	srv := newFakeServer(t, "old")
+	password := &syncValue{value: "old"}
+	log := &recordingLogger{}
+
+	db := sql.OpenDB(mysql.NewConnector(srv.config(), password.provider("app"), log))
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+
+	require.NoError(t, db.Ping())
+
+	// the secrets manager has written the new password, but the server has not got it yet
+	password.set("new")
+	require.NoError(t, db.Ping())
+
+	require.Equal(t, []string{"old", "old"}, srv.logins())
+	require.Equal(t, []string{
+		"database credentials are rotated",
+		"DB connection is established with the previous credentials",
+	}, log.messages())
+}
+
+func TestConnector_GracePeriodExpired(t *testing.T) {
+	srv := newFakeServer(t, "old")
+	password := &syncValue{value: "old"}
+
+	connector := mysql.NewConnector(srv.config(), password.provider("app"), &recordingLogger{}, mysql.WithGracePeriod(0))
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+
+	require.NoError(t, db.Ping())
+
+	password.set("new")
+	err := db.Ping()
+
+	var me *drivermysql.MySQLError
+	require.ErrorAs(t, err, &me)
+	require.Equal(t, uint16(mysql.ErrNumAccessDenied), me.Number)
+}
+
+func TestConnector_ProviderError(t *testing.T) {
+	srv := newFakeServer(t, "secret")
+	errProvider := errors.New("provider")
+	provider := mysql.CredentialsProviderFunc(func(context.Context) (mysql.Credentials, error) {
+		return mysql.Credentials{}, errProvider
+	})
+
+	db := sql.OpenDB(mysql.NewConnector(srv.config(), provider, &recordingLogger{}))
+	defer db.Close()
+
+	require.ErrorIs(t, db.Ping(), errProvider)
+	require.Empty(t, srv.logins())
+}
+
+func TestNewConnection_PasswordFile(t *testing.T) {
+	srv := newFakeServer(t, "old")
+	cfg := srv.config()
+	cfg.PasswordFile = filepath.Join(t.TempDir(), "db_pass")
+	cfg.MaxOpenConnections = 10
+	cfg.MaxIdleConnections = 1
+	require.NoError(t, os.WriteFile(cfg.PasswordFile, []byte("old\n"), 0o600))
+
+	db, err := mysql.NewConnection(cfg, &recordingLogger{})
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+
+	srv.accept("new")
+	require.NoError(t, os.WriteFile(cfg.PasswordFile, []byte("new\n"), 0o600))
+	require.NoError(t, db.Ping())
+
+	require.Equal(t, []string{"old", "new"}, srv.logins())
+}
+
+func TestFileCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_pass")
+	require.NoError(t, os.WriteFile(path, []byte(" secret\n"), 0o600))
+
+	creds, err := mysql.FileCredentials("app", path).Credentials(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, mysql.Credentials{User: "app", Password: "secret"}, creds)
+
+	_, err = mysql.FileCredentials("app", path+".missing").Credentials(context.Background())
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSourceCredentials(t *testing.T) {
+	src := envx.NewMapSource(map[string]string{"DB_USER": "app", "DB_PASS": "secret"}, "secrets")
+
+	creds, err := mysql.SourceCredentials(src, "DB_USER", "DB_PASS").Credentials(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, mysql.Credentials{User: "app", Password: "secret"}, creds)
+
+	_, err = mysql.SourceCredentials(src, "DB_USER", "DB_PASSWORD").Credentials(context.Background())
+	require.ErrorContains(t, err, "'DB_PASSWORD' is not found in secrets")
+}
+
+type syncValue struct {
+	mu    sync.Mutex
+	value string
+}
+
+func (v *syncValue) set(value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.value = value
+}
+
+func (v *syncValue) provider(user string) mysql.CredentialsProvider {
+	return mysql.CredentialsProviderFunc(func(context.Context) (mysql.Credentials, error) {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return mysql.Credentials{User: user, Password: v.value}, nil
+	})
+}
+
+type recordingLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *recordingLogger) Info(msg string, _ ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if msg == "database credentials are rotated" || msg == "DB connection is established with the previous credentials" {
+		l.msgs = append(l.msgs, msg)
+	}
+}
+
+func (l *recordingLogger) messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.msgs...)
+}
+
+// fakeServer is the MySQL server which supports mysql_native_password authentication and ping only
+type fakeServer struct {
+	ln net.Listener
+
+	mu        sync.Mutex
+	passwords []string
+	accepted  []string
+}
+
+func newFakeServer(t *testing.T, passwords ...string) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &fakeServer{ln: ln, passwords: passwords}
+	t.Cleanup(func() { _ = ln.Close() })
+	go srv.serve()
+
+	return srv
+}
+
+func (s *fakeServer) config() *mysql.Config {
+	return &mysql.Config{
+		Host: "127.0.0.1",
+		Port: s.ln.Addr().(*net.TCPAddr).Port,
+		Name: "db_name",
+	}
+}
+
+// accept replaces passwords accepted by the server
+func (s *fakeServer) accept(passwords ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passwords = passwords
+}
+
+// logins returns passwords of successful logins
+func (s *fakeServer) logins() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.accepted...)
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	seed := make([]byte, 20)
+	_, _ = rand.Read(seed)
+	for i := range seed {
+		seed[i] = 'a' + seed[i]%26
+	}
+
+	var caps uint32 = 0x1 | 0x8 | 0x200 | 0x8000 | 0x80000 // long password, with db, protocol 41, secure conn, plugin auth
+	handshake := []byte{10}
+	handshake = append(handshake, "8.0.36\x00"...)
+	handshake = append(handshake, 1, 0, 0, 0)
+	handshake = append(handshake, seed[:8]...)
+	handshake = append(handshake, 0, byte(caps), byte(caps>>8), 33, 2, 0, byte(caps>>16), byte(caps>>24), 21)
+	handshake = append(handshake, make([]byte, 10)...)
+	handshake = append(handshake, seed[8:]...)
+	handshake = append(handshake, 0)
+	handshake = append(handshake, "mysql_native_password\x00"...)
+	if writePacket(conn, 0, handshake) != nil {
+		return
+	}
+
+	response, err := readPacket(conn)
+	if err != nil || len(response) < 33 {
+		return
+	}
+	pos := 32
+	for pos < len(response) && response[pos] != 0 {
+		pos++
+	}
+	pos++
+	n := int(response[pos])
+	auth := string(response[pos+1 : pos+1+n])
+
+	password, ok := s.authenticate(seed, auth)
+	if !ok {
+		_ = writePacket(conn, 2, append([]byte{0xff, 0x15, 0x04, '#', '2', '8', '0', '0', '0'}, "Access denied"...))
+		return
+	}
+	s.mu.Lock()
+	s.accepted = append(s.accepted, password)
+	s.mu.Unlock()
+	if writePacket(conn, 2, okPacket) != nil {
+		return
+	}
+
+	for {
+		cmd, err := readPacket(conn)
+		if err != nil || len(cmd) == 0 || cmd[0] == 0x01 { // quit
+			return
+		}
+		if cmd[0] == 0x0e { // ping
+			err = writePacket(conn, 1, okPacket)
+		} else {
+			err = writePacket(conn, 1, append([]byte{0xff, 0x1b, 0x04, '#', '4', '2', '0', '0', '0'}, "Not supported"...))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) authenticate(seed []byte, auth string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, password := range s.passwords {
+		if string(scramble(seed, password)) == auth {
+			return password, true
+		}
+	}
+	return "", false
+}
+
+var okPacket = []byte{0, 0, 0, 2, 0, 0, 0}
+
+// scramble hashes the password as mysql_native_password does: SHA1(password) XOR SHA1(seed + SHA1(SHA1(password)))
+func scramble(seed []byte, password string) []byte {
+	if password == "" {
+		return nil
+	}
+	h1 := sha1.Sum([]byte(password))
+	h2 := sha1.Sum(h1[:])
+	h3 := sha1.Sum(append(append([]byte(nil), seed...), h2[:]...))
+	for i := range h3 {
+		h3[i] ^= h1[i]
+	}
+	return h3[:]
+}
+
+func writePacket(conn net.Conn, seq byte, payload []byte) error {
+	header := []byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}
+	_, err := conn.Write(append(header, payload...))
+	return err
+}
+
+func readPacket(conn net.Conn) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.LittleEndian.Uint32(append(header[:3], 0)))
+	_, err := io.ReadFull(conn, payload)
+	return payload, err
+}
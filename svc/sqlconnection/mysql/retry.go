@@ -0,0 +1,25 @@
+package mysql
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error numbers caused by concurrent transactions
+const (
+	ErrNumLockWaitTimeout = 1205
+	ErrNumDeadlock        = 1213
+)
+
+// IsRetryable reports whether the transaction failed with the error is worth retrying, i.e. it is a deadlock
+// or a lock wait timeout. The function satisfies sqltx.ClassifierFunc:
+//
+//	policy := sqltx.RetryPolicy{Classifier: sqltx.ClassifierFunc(mysql.IsRetryable)}
+func IsRetryable(err error) bool {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	return me.Number == ErrNumDeadlock || me.Number == ErrNumLockWaitTimeout
+}
@@ -0,0 +1,20 @@
+package mysql_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/velmie/x/svc/sqlconnection/mysql"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, mysql.IsRetryable(&driver.MySQLError{Number: mysql.ErrNumDeadlock}))
+	assert.True(t, mysql.IsRetryable(fmt.Errorf("commit: %w", &driver.MySQLError{Number: mysql.ErrNumLockWaitTimeout})))
+	assert.False(t, mysql.IsRetryable(&driver.MySQLError{Number: 1062}))
+	assert.False(t, mysql.IsRetryable(errors.New("deadlock")))
+	assert.False(t, mysql.IsRetryable(nil))
+}
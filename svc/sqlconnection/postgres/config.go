@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/velmie/x/envx"
+)
+
+// SSL modes supported by libpq and pgx
+const (
+	SSLModeDisable    = "disable"
+	SSLModeAllow      = "allow"
+	SSLModePrefer     = "prefer"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+const (
+	defaultDBSSLMode = SSLModeVerifyFull
+)
+
+type Config struct {
+	Host     string
+	Port     int
+	Name     string
+	User     string
+	Password string
+
+	SSLMode         string // one of the SSLMode constants, leave empty to use verify-full
+	SSLRootCertPath string // leave empty to verify the server certificate with the system root CAs
+	SSLCertPath     string // client certificate
+	SSLKeyPath      string // client certificate key
+
+	SearchPath       []string
+	StatementTimeout time.Duration // leave 0 to disable the timeout
+	ApplicationName  string
+
+	MaxOpenConnections int           // leave 0 to use default value
+	MaxIdleConnections int           // leave 0 to use default value
+	ConnMaxIdleTime    time.Duration // leave 0 to use default value
+	ConnMaxLifetime    time.Duration // leave 0 to use default value
+}
+
+func ConfigFromEnv(envPrefix string) (*Config, error) {
+	res := &Config{}
+	p := envx.CreatePrototype().WithPrefix(envPrefix)
+
+	const (
+		envNameSSLMode         = "DB_SSL_MODE"
+		envNameSSLRootCertPath = "DB_SSL_ROOT_CERT_PATH"
+		envNameSSLCertPath     = "DB_SSL_CERT_PATH"
+		envNameSSLKeyPath      = "DB_SSL_KEY_PATH"
+	)
+
+	err := envx.Supply(
+		envx.Set(&res.Host, p.Get("DB_HOST").Required().NotEmpty().ValidDomainName().String),
+		envx.Set(&res.Port, p.Get("DB_PORT").Required().NotEmpty().ValidPortNumber().Int),
+		envx.Set(&res.Name, p.Get("DB_NAME").Required().NotEmpty().String),
+		envx.Set(&res.User, p.Get("DB_USER").Required().NotEmpty().String),
+		envx.Set(&res.Password, p.Get("DB_PASS").Required().NotEmpty().String),
+
+		envx.Set(&res.SearchPath, func() ([]string, error) { return p.Get("DB_SEARCH_PATH").StringSlice() }),
+		envx.Set(&res.StatementTimeout, p.Get("DB_STATEMENT_TIMEOUT").Duration),
+		envx.Set(&res.ApplicationName, p.Get("DB_APPLICATION_NAME").String),
+
+		envx.Set(&res.MaxOpenConnections, p.Get("DB_MAX_OPEN_CONNECTIONS").Int),
+		envx.Set(&res.MaxIdleConnections, p.Get("DB_MAX_IDLE_CONNECTIONS").Int),
+		envx.Set(&res.ConnMaxLifetime, p.Get("DB_CONNECTION_MAX_LIFETIME").Duration),
+		envx.Set(&res.ConnMaxIdleTime, p.Get("DB_CONNECTION_MAX_IDLE_TIME").Duration),
+
+		envx.Set(&res.SSLMode, p.Get(envNameSSLMode).Default(defaultDBSSLMode).OneOf(
+			SSLModeDisable, SSLModeAllow, SSLModePrefer, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull,
+		).String),
+		envx.Set(&res.SSLRootCertPath, p.Get(envNameSSLRootCertPath).String),
+		envx.Set(&res.SSLCertPath, p.Get(envNameSSLCertPath).String),
+		envx.Set(&res.SSLKeyPath, p.Get(envNameSSLKeyPath).String),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = envx.Supply(
+		envx.Set(&res.SSLCertPath, p.Get(envNameSSLCertPath).RequiredIf(res.SSLKeyPath != "").String),
+		envx.Set(&res.SSLKeyPath, p.Get(envNameSSLKeyPath).RequiredIf(res.SSLCertPath != "").String),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w. Client certificate requires both the certificate and the key", err)
+	}
+
+	if res.SSLRootCertPath != "" {
+		pemFile, inErr := os.ReadFile(res.SSLRootCertPath)
+		if inErr != nil {
+			return nil, fmt.Errorf(
+				"the environment variable '%s' has invalid value: %w",
+				withPrefix(envPrefix, envNameSSLRootCertPath), inErr,
+			)
+		}
+		if ok := x509.NewCertPool().AppendCertsFromPEM(pemFile); !ok {
+			return nil, fmt.Errorf(
+				"the environment variable '%s' has invalid value. Please make sure you use a PEM file",
+				withPrefix(envPrefix, envNameSSLRootCertPath),
+			)
+		}
+	}
+
+	if res.SSLCertPath != "" {
+		if _, inErr := tls.LoadX509KeyPair(res.SSLCertPath, res.SSLKeyPath); inErr != nil {
+			return nil, fmt.Errorf(
+				"the environment variables '%s' and '%s' have invalid values: %w",
+				withPrefix(envPrefix, envNameSSLCertPath), withPrefix(envPrefix, envNameSSLKeyPath), inErr,
+			)
+		}
+	}
+
+	return res, nil
+}
+
+func withPrefix(prefix, name string) string {
+	return prefix + name
+}
@@ -0,0 +1,217 @@
+package postgres_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/svc/sqlconnection/postgres"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	certPath, keyPath := writeCertificate(t)
+
+	envs := []string{
+		"DB_HOST",
+		"DB_PORT",
+		"DB_NAME",
+		"DB_USER",
+		"DB_PASS",
+		"DB_SSL_MODE",
+		"DB_SSL_ROOT_CERT_PATH",
+		"DB_SSL_CERT_PATH",
+		"DB_SSL_KEY_PATH",
+		"DB_SEARCH_PATH",
+		"DB_STATEMENT_TIMEOUT",
+		"DB_APPLICATION_NAME",
+		"DB_MAX_OPEN_CONNECTIONS",
+		"DB_MAX_IDLE_CONNECTIONS",
+		"DB_CONNECTION_MAX_LIFETIME",
+		"DB_CONNECTION_MAX_IDLE_TIME",
+	}
+
+	required := map[string]string{
+		"DB_HOST": "localhost",
+		"DB_PORT": "5432",
+		"DB_NAME": "db_name",
+		"DB_USER": "db_user",
+		"DB_PASS": "db_secret",
+	}
+
+	tests := []struct {
+		name         string
+		envs         map[string]string
+		expectsError bool
+	}{
+		{
+			name:         "Host is missed",
+			envs:         map[string]string{"DB_HOST": ""},
+			expectsError: true,
+		},
+		{
+			name:         "Port is invalid",
+			envs:         map[string]string{"DB_PORT": "99999"},
+			expectsError: true,
+		},
+		{
+			name:         "SSL mode is invalid",
+			envs:         map[string]string{"DB_SSL_MODE": "verify"},
+			expectsError: true,
+		},
+		{
+			name:         "SSL root certificate does not exist",
+			envs:         map[string]string{"DB_SSL_ROOT_CERT_PATH": "/not/exist.pem"},
+			expectsError: true,
+		},
+		{
+			name:         "SSL root certificate is not PEM",
+			envs:         map[string]string{"DB_SSL_ROOT_CERT_PATH": writeFile(t, "root.txt", "not a certificate")},
+			expectsError: true,
+		},
+		{
+			name:         "Client certificate key is missed",
+			envs:         map[string]string{"DB_SSL_CERT_PATH": certPath},
+			expectsError: true,
+		},
+		{
+			name:         "Client certificate is missed",
+			envs:         map[string]string{"DB_SSL_KEY_PATH": keyPath},
+			expectsError: true,
+		},
+		{
+			name: "Client certificate does not match the key",
+			envs: map[string]string{
+				"DB_SSL_CERT_PATH": certPath,
+				"DB_SSL_KEY_PATH":  certPath,
+			},
+			expectsError: true,
+		},
+		{
+			name:         "Statement timeout is invalid",
+			envs:         map[string]string{"DB_STATEMENT_TIMEOUT": "5"},
+			expectsError: true,
+		},
+		{
+			name:         "everything is ok: only required vars",
+			envs:         map[string]string{},
+			expectsError: false,
+		},
+		{
+			name: "everything is ok: all vars",
+			envs: map[string]string{
+				"DB_SSL_MODE":                 "verify-full",
+				"DB_SSL_ROOT_CERT_PATH":       certPath,
+				"DB_SSL_CERT_PATH":            certPath,
+				"DB_SSL_KEY_PATH":             keyPath,
+				"DB_SEARCH_PATH":              "app,public",
+				"DB_STATEMENT_TIMEOUT":        "5s",
+				"DB_APPLICATION_NAME":         "billing",
+				"DB_MAX_OPEN_CONNECTIONS":     "10",
+				"DB_MAX_IDLE_CONNECTIONS":     "1",
+				"DB_CONNECTION_MAX_LIFETIME":  "1m",
+				"DB_CONNECTION_MAX_IDLE_TIME": "10m",
+			},
+			expectsError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range envs {
+				_ = os.Unsetenv(name)
+			}
+			for k, v := range required {
+				require.NoError(t, os.Setenv(k, v))
+			}
+			for k, v := range tt.envs {
+				require.NoError(t, os.Setenv(k, v))
+			}
+
+			_, err := postgres.ConfigFromEnv("")
+			if tt.expectsError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv_Values(t *testing.T) {
+	certPath, keyPath := writeCertificate(t)
+
+	t.Setenv("PFX_DB_HOST", "db.example.com")
+	t.Setenv("PFX_DB_PORT", "5433")
+	t.Setenv("PFX_DB_NAME", "db_name")
+	t.Setenv("PFX_DB_USER", "db_user")
+	t.Setenv("PFX_DB_PASS", "db_secret")
+	t.Setenv("PFX_DB_SSL_ROOT_CERT_PATH", certPath)
+	t.Setenv("PFX_DB_SSL_CERT_PATH", certPath)
+	t.Setenv("PFX_DB_SSL_KEY_PATH", keyPath)
+	t.Setenv("PFX_DB_SEARCH_PATH", "app,public")
+	t.Setenv("PFX_DB_STATEMENT_TIMEOUT", "30s")
+	t.Setenv("PFX_DB_APPLICATION_NAME", "billing")
+
+	cfg, err := postgres.ConfigFromEnv("PFX_")
+	require.NoError(t, err)
+	require.Equal(t, &postgres.Config{
+		Host:             "db.example.com",
+		Port:             5433,
+		Name:             "db_name",
+		User:             "db_user",
+		Password:         "db_secret",
+		SSLMode:          postgres.SSLModeVerifyFull,
+		SSLRootCertPath:  certPath,
+		SSLCertPath:      certPath,
+		SSLKeyPath:       keyPath,
+		SearchPath:       []string{"app", "public"},
+		StatementTimeout: 30 * time.Second,
+		ApplicationName:  "billing",
+	}, cfg)
+}
+
+// writeCertificate writes the self-signed certificate and its key, the certificate is also used as the root CA
+func writeCertificate(t *testing.T) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath = writeFile(t, "cert.pem", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	keyPath = writeFile(t, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	return certPath, keyPath
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
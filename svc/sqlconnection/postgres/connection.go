@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	defaultConnMaxIdleTime = 10 * time.Minute
+	defaultConnMaxLifetime = 1 * time.Hour
+)
+
+type Logger interface {
+	Info(msg string, args ...any)
+}
+
+// ConnConfig creates pgx connection config, e.g. in order to use it with pgxpool
+func ConnConfig(cfg *Config) (*pgx.ConnConfig, error) {
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = defaultDBSSLMode
+	}
+
+	params := []string{
+		"host=" + quote(cfg.Host),
+		"port=" + strconv.Itoa(cfg.Port),
+		"dbname=" + quote(cfg.Name),
+		"user=" + quote(cfg.User),
+		"password=" + quote(cfg.Password),
+		"sslmode=" + quote(sslMode),
+	}
+	if cfg.SSLRootCertPath != "" {
+		params = append(params, "sslrootcert="+quote(cfg.SSLRootCertPath))
+	}
+	if cfg.SSLCertPath != "" {
+		params = append(params, "sslcert="+quote(cfg.SSLCertPath), "sslkey="+quote(cfg.SSLKeyPath))
+	}
+	if len(cfg.SearchPath) > 0 {
+		params = append(params, "search_path="+quote(strings.Join(cfg.SearchPath, ",")))
+	}
+	if cfg.StatementTimeout > 0 {
+		params = append(params, "statement_timeout="+strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	if cfg.ApplicationName != "" {
+		params = append(params, "application_name="+quote(cfg.ApplicationName))
+	}
+
+	connCfg, err := pgx.ParseConfig(strings.Join(params, " "))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse postgres config: %w", err)
+	}
+
+	return connCfg, nil
+}
+
+// NewConnection creates new database connection
+func NewConnection(cfg *Config, log Logger) (*sql.DB, error) {
+	connCfg, err := ConnConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := stdlib.OpenDB(*connCfg)
+
+	err = db.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("postgres connection is not established: %w", err)
+	}
+	if connCfg.TLSConfig != nil {
+		log.Info("TLS DB connection is established")
+	} else {
+		log.Info("DB connection is established")
+	}
+
+	if cfg.MaxIdleConnections == 0 || cfg.MaxOpenConnections == 0 {
+		var maxConn int
+		err = db.QueryRow("SELECT current_setting('max_connections')::int").Scan(&maxConn)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get maximum number of connections: %w", err)
+		}
+
+		const (
+			maxOpenConnsCoefficient = .9
+			maxIdleConnsCoefficient = .1
+		)
+
+		if cfg.MaxIdleConnections == 0 {
+			maxIdleConn := int(float64(maxConn) * maxIdleConnsCoefficient)
+			if maxIdleConn < 1 {
+				maxIdleConn = 1
+			}
+			cfg.MaxIdleConnections = maxIdleConn
+		}
+		if cfg.MaxOpenConnections == 0 {
+			maxC := int(float64(maxConn) * maxOpenConnsCoefficient)
+			if maxC < 1 {
+				maxC = 1
+			}
+			cfg.MaxOpenConnections = maxC
+		}
+	}
+
+	if cfg.ConnMaxIdleTime == 0 {
+		cfg.ConnMaxIdleTime = defaultConnMaxIdleTime
+	}
+
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = defaultConnMaxLifetime
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConnections)
+	db.SetMaxIdleConns(cfg.MaxIdleConnections)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	log.Info("maximum number of database connections is set", "maxConn", cfg.MaxOpenConnections)
+	log.Info("maximum number of idle database connections is set", "maxIdleConn", cfg.MaxIdleConnections)
+	log.Info("maximum life time of idle database connections is set", "minutes", cfg.ConnMaxIdleTime.Minutes())
+	log.Info("maximum life time of database connections is set", "minutes", cfg.ConnMaxLifetime.Minutes())
+
+	return db, nil
+}
+
+// quote quotes the value of the keyword/value connection string
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
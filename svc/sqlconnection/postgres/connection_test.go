@@ -0,0 +1,75 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/velmie/x/svc/sqlconnection/postgres"
+)
+
+func TestConnConfig(t *testing.T) {
+	cfg := &postgres.Config{
+		Host:             "localhost",
+		Port:             5432,
+		Name:             "db_name",
+		User:             "db_user",
+		Password:         `it's a \secret`,
+		SSLMode:          postgres.SSLModeDisable,
+		SearchPath:       []string{"app", "public"},
+		StatementTimeout: 1500 * time.Millisecond,
+		ApplicationName:  "billing service",
+	}
+
+	connCfg, err := postgres.ConnConfig(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "localhost", connCfg.Host)
+	require.Equal(t, uint16(5432), connCfg.Port)
+	require.Equal(t, "db_name", connCfg.Database)
+	require.Equal(t, "db_user", connCfg.User)
+	require.Equal(t, `it's a \secret`, connCfg.Password)
+	require.Nil(t, connCfg.TLSConfig)
+	require.Empty(t, connCfg.Fallbacks)
+	require.Equal(t, "app,public", connCfg.RuntimeParams["search_path"])
+	require.Equal(t, "1500", connCfg.RuntimeParams["statement_timeout"])
+	require.Equal(t, "billing service", connCfg.RuntimeParams["application_name"])
+}
+
+func TestConnConfig_VerifyFull(t *testing.T) {
+	certPath, keyPath := writeCertificate(t)
+
+	connCfg, err := postgres.ConnConfig(&postgres.Config{
+		Host:            "localhost",
+		Port:            5432,
+		Name:            "db_name",
+		User:            "db_user",
+		Password:        "db_secret",
+		SSLRootCertPath: certPath,
+		SSLCertPath:     certPath,
+		SSLKeyPath:      keyPath,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, connCfg.TLSConfig)
+	require.Equal(t, "localhost", connCfg.TLSConfig.ServerName)
+	require.NotNil(t, connCfg.TLSConfig.RootCAs)
+	require.Len(t, connCfg.TLSConfig.Certificates, 1)
+	require.Empty(t, connCfg.Fallbacks)
+	require.NotContains(t, connCfg.RuntimeParams, "search_path")
+	require.NotContains(t, connCfg.RuntimeParams, "statement_timeout")
+}
+
+func TestConnConfig_Prefer(t *testing.T) {
+	connCfg, err := postgres.ConnConfig(&postgres.Config{
+		Host:     "localhost",
+		Port:     5432,
+		Name:     "db_name",
+		User:     "db_user",
+		Password: "db_secret",
+		SSLMode:  postgres.SSLModePrefer,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, connCfg.TLSConfig)
+	require.Len(t, connCfg.Fallbacks, 1)
+	require.Nil(t, connCfg.Fallbacks[0].TLSConfig)
+}
@@ -0,0 +1,21 @@
+module github.com/velmie/x/svc/sqlconnection/postgres
+
+go 1.22.4
+
+require (
+	github.com/jackc/pgx/v5 v5.7.2
+	github.com/stretchr/testify v1.11.1
+	github.com/velmie/x/envx v0.9.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)